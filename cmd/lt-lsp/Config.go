@@ -15,33 +15,48 @@ func init() {
 }
 
 var Configure = &cobra.Command{
-	Use:   "configure <username> <api-token>",
+	Use:   "configure [<username> <api-token>]",
 	Short: "Configure the LSP (set api Token and username)",
+	Long: "Configure the LSP (set api Token and username). The credentials can be omitted " +
+		"for a self-hosted server or the free API, which are set with --base-url.",
 	Args: func(cmd *cobra.Command, args []string) error {
-		if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+		if err := cobra.MaximumNArgs(2)(cmd, args); err != nil {
 			return err
 		}
 		client := languagetool.NewClient(zap.NewNop())
-		client = client.WithCredentials(languagetool.Credentials{
-			Username: args[0],
-			ApiToken: args[1],
-		})
+		client = client.WithBaseURL(viper.GetString("baseURL"))
+		switch len(args) {
+		case 0:
+			if !client.IsSelfHosted() && !client.IsPublic() {
+				return errors.New("the username and the api token are required for the LanguageTool API, use --base-url for a self-hosted server")
+			}
+		case 1:
+			return errors.New("the api token is missing")
+		default:
+			client = client.WithCredentials(languagetool.Credentials{
+				Username: args[0],
+				ApiToken: args[1],
+			})
+		}
 		// make a request to check if the credentials are valid
-		result, err := client.CheckText(context.Background(), "Lorem ipsum dolor sit amet, qui minim labore adipisicing minim sint cillum sint consectetur cupidatat.")
+		result, err := client.CheckText(context.Background(), "Lorem ipsum dolor sit amet, qui minim labore adipisicing minim sint cillum sint consectetur cupidatat.", languagetool.CheckOptions{})
 
 		if err != nil {
 			return err
 		}
 
-		if !result.Software.Premium {
+		// only the premium API knows about premium accounts
+		if !client.IsSelfHosted() && !client.IsPublic() && !result.Software.Premium {
 			return errors.New("Tried to call the api but don't received a premium response")
 		}
 
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		viper.Set("username", args[0])
-		viper.Set("apiToken", args[1])
+		if len(args) == 2 {
+			viper.Set("username", args[0])
+			viper.Set("apiToken", args[1])
+		}
 		viper.WriteConfig()
 	},
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/pascal-sochacki/languagetool-lsp/internal"
 	"github.com/pascal-sochacki/languagetool-lsp/internal/server"
@@ -17,6 +18,12 @@ import (
 
 func init() {
 	RootCmd.AddCommand(LSPRun)
+	viper.SetDefault("retries", 3)
+	viper.SetDefault("parallel", 2)
+	viper.SetDefault("cache.size", 1000)
+	viper.SetDefault("cache.ttl", time.Hour)
+	viper.SetDefault("cache.persist", true)
+	viper.SetDefault("debounce", 500*time.Millisecond)
 }
 
 var LSPRun = &cobra.Command{
@@ -60,15 +67,86 @@ var LSPRun = &cobra.Command{
 		log.Info("starting...")
 
 		languagetoolClient := *languagetool.NewClient(log)
+		languagetoolClient = *languagetoolClient.WithBaseURL(viper.GetString("baseURL"))
 		languagetoolClient = *languagetoolClient.WithCredentials(languagetool.Credentials{
 			Username: viper.GetString("username"),
 			ApiToken: viper.GetString("apitoken"),
 		})
 
+		plan, err := selectPlan(languagetoolClient)
+		if err != nil {
+			log.Error(err.Error())
+			plan = languagetool.FreePlan
+		}
+		limiter := languagetool.NewRateLimiter(log, languagetoolClient, plan, viper.GetInt("retries"))
+
+		chunkSize := viper.GetInt("chunkSize")
+		if chunkSize == 0 {
+			chunkSize = plan.MaxRequestBytes
+		}
+		chunker := languagetool.NewChunker(limiter, chunkSize, viper.GetInt("parallel"))
+
+		var api languagetool.LanguagetoolApi = chunker
+		if size := viper.GetInt("cache.size"); size > 0 {
+			store, err := newCacheStore(log, size, viper.GetDuration("cache.ttl"))
+			if err != nil {
+				log.Error(err.Error())
+				store = languagetool.NewMemoryStore(size, viper.GetDuration("cache.ttl"))
+			}
+			if fileStore, ok := store.(*languagetool.FileStore); ok {
+				defer func() {
+					if err := fileStore.Close(); err != nil {
+						log.Error(err.Error())
+					}
+				}()
+			}
+			api = languagetool.NewCache(chunker, store)
+		}
+
+		config := server.Config{Debounce: viper.GetDuration("debounce")}
+		config.Dictionary = viper.GetString("dictionary.file")
+		if config.Dictionary == "" {
+			config.Dictionary, err = server.DefaultDictionaryFile()
+			if err != nil {
+				log.Error(err.Error())
+			}
+		}
+		config.DisabledRulesFile = viper.GetString("disabledRulesFile")
+		if config.DisabledRulesFile == "" {
+			config.DisabledRulesFile, err = server.DefaultDisabledRulesFile()
+			if err != nil {
+				log.Error(err.Error())
+			}
+		}
+		config.DocumentRulesFile = viper.GetString("documentRulesFile")
+		if config.DocumentRulesFile == "" {
+			config.DocumentRulesFile, err = server.DefaultDocumentRulesFile()
+			if err != nil {
+				log.Error(err.Error())
+			}
+		}
+		if viper.GetBool("dictionary.account") {
+			config.Words = languagetoolClient
+		}
+		if err := viper.UnmarshalKey("check", &config.Check); err != nil {
+			log.Error(err.Error())
+		}
+		if err := viper.UnmarshalKey("severity", &config.Severity); err != nil {
+			log.Error(err.Error())
+		}
+		if err := viper.UnmarshalKey("gitCommit", &config.GitCommit); err != nil {
+			log.Error(err.Error())
+		}
+		if err := viper.UnmarshalKey("markup", &config.Markup); err != nil {
+			log.Error(err.Error())
+		}
+
 		stream := jsonrpc2.NewStream(internal.StdReaderWriterCloser{Log: log})
-		server, serverInit := server.NewServer(log, languagetoolClient)
+		server, serverInit := server.NewServer(log, api, config)
 
-		_, conn, client := protocol.NewServer(ctx, server, stream, log)
+		conn := jsonrpc2.NewConn(stream)
+		client := protocol.ClientDispatcher(conn, log.Named("client"))
+		conn.Go(protocol.WithClient(ctx, client), protocol.Handlers(server.Handler()))
 		defer conn.Close()
 
 		serverInit(client)
@@ -84,6 +162,42 @@ var LSPRun = &cobra.Command{
 	},
 }
 
+// selectPlan returns the configured plan or guesses it from the endpoint
+// and the credentials.
+func selectPlan(client languagetool.Client) (languagetool.Plan, error) {
+	if name := viper.GetString("plan"); name != "" {
+		return languagetool.PlanByName(name)
+	}
+	if client.IsPublic() {
+		return languagetool.FreePlan, nil
+	}
+	if client.IsSelfHosted() {
+		return languagetool.UnlimitedPlan, nil
+	}
+	if viper.GetString("username") != "" {
+		return languagetool.PremiumPlan, nil
+	}
+	return languagetool.FreePlan, nil
+}
+
+// newCacheStore returns the file backed store shared by all instances or
+// an in-memory one if persisting is disabled.
+func newCacheStore(log *zap.Logger, size int, ttl time.Duration) (languagetool.CacheStore, error) {
+	if !viper.GetBool("cache.persist") {
+		return languagetool.NewMemoryStore(size, ttl), nil
+	}
+
+	path := viper.GetString("cache.file")
+	if path == "" {
+		var err error
+		path, err = languagetool.DefaultCacheFile()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return languagetool.NewFileStore(log, path, size, ttl)
+}
+
 func NewLogger() (*zap.Logger, error) {
 	cfg := zap.NewProductionConfig()
 	cfg.OutputPaths = []string{
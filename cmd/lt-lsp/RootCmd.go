@@ -3,8 +3,10 @@ package ltlsp
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/mitchellh/go-homedir"
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -14,7 +16,14 @@ var cfgFile string
 func init() {
 	cobra.OnInitialize(initConfig)
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.lt-lsp)")
+	RootCmd.PersistentFlags().String("base-url", "", "LanguageTool server to use, e.g. http://localhost:8081/v2/ (default is "+languagetool.DefaultBaseURL+")")
 	viper.BindPFlag("uername", RootCmd.PersistentFlags().Lookup("username"))
+	viper.BindPFlag("baseURL", RootCmd.PersistentFlags().Lookup("base-url"))
+
+	// every setting can be overwritten by the environment, e.g. LT_LSP_BASEURL
+	viper.SetEnvPrefix("lt_lsp")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
 }
 
 func initConfig() {
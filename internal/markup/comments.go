@@ -0,0 +1,305 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+)
+
+// commentSyntax describes the comments and string literals of a
+// programming language. Strings are skipped, so comment markers in them are
+// not mistaken for comments.
+type commentSyntax struct {
+	// line starts comments until the end of the line, e.g. "//".
+	line string
+	// blockStart and blockEnd enclose block comments, nested ones if nested
+	// is set.
+	blockStart, blockEnd string
+	nested               bool
+	// strings are the delimiters of string literals, longer ones first.
+	strings []string
+	// raw are the delimiters of strings without escape sequences.
+	raw []string
+	// multiline is set if strings with a single delimiter may span lines,
+	// otherwise a line break ends an unterminated string.
+	multiline bool
+	// docstrings checks strings with three quotes which are a statement of
+	// their own, like the docstrings of Python.
+	docstrings bool
+	// wordStart requires comments to start at the beginning of a word, as
+	// $# is no comment in shell scripts.
+	wordStart bool
+	// shebang skips a first line starting with #!.
+	shebang bool
+	// lifetimes are the 'a of Rust, which are no character literals.
+	lifetimes bool
+	// directives are prefixes of comments for tools, e.g. "go:generate".
+	directives []string
+}
+
+var (
+	cSyntax = commentSyntax{
+		line: "//", blockStart: "/*", blockEnd: "*/",
+		strings: []string{`"`, `'`},
+	}
+	goSyntax = commentSyntax{
+		line: "//", blockStart: "/*", blockEnd: "*/",
+		strings: []string{`"`, `'`}, raw: []string{"`"},
+		directives: []string{"go:", "+build", "nolint", "export ", "line "},
+	}
+	javaSyntax = commentSyntax{
+		line: "//", blockStart: "/*", blockEnd: "*/",
+		strings: []string{`"""`, `"`, `'`},
+	}
+	javascriptSyntax = commentSyntax{
+		line: "//", blockStart: "/*", blockEnd: "*/",
+		strings:    []string{`"`, `'`, "`"},
+		shebang:    true,
+		directives: []string{"eslint", "@ts-", "prettier-ignore", "istanbul ", "/ <reference", "#region", "#endregion"},
+	}
+	rustSyntax = commentSyntax{
+		line: "//", blockStart: "/*", blockEnd: "*/", nested: true,
+		strings: []string{`"`, `'`}, multiline: true, lifetimes: true,
+	}
+	pythonSyntax = commentSyntax{
+		line:    "#",
+		strings: []string{`"""`, `'''`, `"`, `'`}, docstrings: true,
+		shebang:    true,
+		directives: []string{"type:", "noqa", "pragma", "pylint:", "-*-", "fmt:"},
+	}
+	shellSyntax = commentSyntax{
+		line:    "#",
+		strings: []string{`"`}, raw: []string{`'`}, multiline: true,
+		wordStart: true, shebang: true,
+		directives: []string{"shellcheck "},
+	}
+)
+
+// commentTag matches the tags of JSDoc, Javadoc and Doxygen at the start of
+// a line, with the type and the name of parameters.
+var commentTag = regexp.MustCompile(`^[@\\](?:(?:param|arg|argument|tparam|throws|exception|template|typedef|property|prop)(?:\s+\{[^}]*\})?(?:\s+\[?[\w.$]+\]?)?|\w+(?:\s+\{[^}]*\})?)`)
+
+// comments returns an Extractor for source code, only the comments are
+// checked. The comment markers and the code are markup.
+func comments(syntax commentSyntax) Extractor {
+	return func(text string) languagetool.AnnotatedText {
+		c := &commentScanner{syntax: syntax, source: text}
+		c.scan()
+		return c.result()
+	}
+}
+
+type commentScanner struct {
+	builder
+	syntax commentSyntax
+	source string
+	// code is the start of the code not added yet.
+	code int
+}
+
+func (c *commentScanner) scan() {
+	i := 0
+	if c.syntax.shebang && strings.HasPrefix(c.source, "#!") {
+		i = lineEnd(c.source, 0)
+	}
+
+	for i < len(c.source) {
+		rest := c.source[i:]
+		switch {
+		case c.syntax.line != "" && strings.HasPrefix(rest, c.syntax.line) && c.commentStart(i):
+			i = c.lineComment(i)
+		case c.syntax.blockStart != "" && strings.HasPrefix(rest, c.syntax.blockStart):
+			i = c.blockComment(i)
+		case c.syntax.lifetimes && rest[0] == '\'' && !isCharLiteral(rest):
+			i++
+		default:
+			if delimiter, raw, ok := c.stringStart(rest); ok {
+				i = c.stringLiteral(i, delimiter, raw)
+				continue
+			}
+			i++
+		}
+	}
+	c.flushCode(len(c.source))
+}
+
+// commentStart reports whether a line comment may start at i.
+func (c *commentScanner) commentStart(i int) bool {
+	if !c.syntax.wordStart || i == 0 {
+		return true
+	}
+	return strings.ContainsRune(" \t\n;|&(", rune(c.source[i-1]))
+}
+
+func (c *commentScanner) stringStart(rest string) (string, bool, bool) {
+	for _, delimiter := range c.syntax.strings {
+		if strings.HasPrefix(rest, delimiter) {
+			return delimiter, false, true
+		}
+	}
+	for _, delimiter := range c.syntax.raw {
+		if strings.HasPrefix(rest, delimiter) {
+			return delimiter, true, true
+		}
+	}
+	return "", false, false
+}
+
+// stringLiteral skips a string and returns the index after it. Docstrings
+// are checked like comments.
+func (c *commentScanner) stringLiteral(i int, delimiter string, raw bool) int {
+	start := i + len(delimiter)
+	end := start
+	for end < len(c.source) && !strings.HasPrefix(c.source[end:], delimiter) {
+		if !raw && c.source[end] == '\\' {
+			end++
+		} else if len(delimiter) == 1 && !raw && !c.syntax.multiline && c.source[end] == '\n' {
+			// unterminated string, the line break ends it
+			return end
+		}
+		end++
+	}
+	if end >= len(c.source) {
+		return len(c.source)
+	}
+
+	if c.syntax.docstrings && len(delimiter) == 3 && c.statementStart(i) {
+		c.flushCode(i)
+		c.markup(delimiter, "")
+		c.commentText(c.source[start:end])
+		c.markup(delimiter, "")
+		c.code = end + len(delimiter)
+	}
+	return end + len(delimiter)
+}
+
+// statementStart reports whether only whitespace is in front of i on its
+// line.
+func (c *commentScanner) statementStart(i int) bool {
+	lineStart := strings.LastIndexByte(c.source[:i], '\n') + 1
+	return strings.TrimSpace(c.source[lineStart:i]) == ""
+}
+
+func (c *commentScanner) lineComment(i int) int {
+	c.flushCode(i)
+	end := lineEnd(c.source, i)
+
+	marker := i + len(c.syntax.line)
+	for marker < end && strings.ContainsRune(c.syntax.line+"!", rune(c.source[marker])) {
+		marker++
+	}
+	c.markup(c.source[i:marker], "")
+	c.commentText(c.source[marker:end])
+	c.code = end
+	return end
+}
+
+func (c *commentScanner) blockComment(i int) int {
+	c.flushCode(i)
+
+	start := i + len(c.syntax.blockStart)
+	for start < len(c.source) && strings.ContainsRune("*!", rune(c.source[start])) && !strings.HasPrefix(c.source[start:], c.syntax.blockEnd) {
+		start++
+	}
+	c.markup(c.source[i:start], "")
+
+	end, depth := start, 1
+	for end < len(c.source) {
+		if strings.HasPrefix(c.source[end:], c.syntax.blockEnd) {
+			depth--
+			if depth == 0 {
+				break
+			}
+			end += len(c.syntax.blockEnd)
+			continue
+		}
+		if c.syntax.nested && strings.HasPrefix(c.source[end:], c.syntax.blockStart) {
+			depth++
+			end += len(c.syntax.blockStart)
+			continue
+		}
+		end++
+	}
+
+	for n, line := range splitLines(c.source[start:end]) {
+		if n > 0 {
+			// the decoration in front of the lines of the comment
+			prefix := len(line) - len(strings.TrimLeft(line, " \t"))
+			if strings.HasPrefix(line[prefix:], "*") && !strings.HasPrefix(line[prefix:], c.syntax.blockEnd) {
+				prefix++
+				if strings.HasPrefix(line[prefix:], " ") {
+					prefix++
+				}
+			}
+			c.markup(line[:prefix], "")
+			line = line[prefix:]
+		}
+		c.commentText(line)
+	}
+
+	closing := end
+	if end < len(c.source) {
+		closing += len(c.syntax.blockEnd)
+	}
+	c.markup(c.source[end:closing], "")
+	c.code = closing
+	return closing
+}
+
+// commentText adds the text of a comment, directives for tools and tags
+// of documentation comments are markup.
+func (c *commentScanner) commentText(text string) {
+	trimmed := strings.TrimLeft(text, " \t")
+	for _, directive := range c.syntax.directives {
+		if strings.HasPrefix(trimmed, directive) {
+			c.markup(text, "")
+			return
+		}
+	}
+
+	for _, line := range splitLines(text) {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if tag := commentTag.FindString(line[indent:]); tag != "" {
+			c.text(line[:indent])
+			c.markup(tag, "")
+			line = line[indent+len(tag):]
+		}
+		c.text(line)
+	}
+}
+
+// flushCode adds the code in front of end as markup. Code separates the
+// comments around it like a blank line, only indentation does not.
+func (c *commentScanner) flushCode(end int) {
+	if end <= c.code {
+		return
+	}
+	code := c.source[c.code:end]
+	interpretAs := ""
+	if strings.TrimSpace(code) != "" || strings.Contains(code, "\n") {
+		interpretAs = paragraphBreak
+	}
+	c.markup(code, interpretAs)
+	c.code = end
+}
+
+// lineEnd returns the index after the line break of the line at i.
+func lineEnd(text string, i int) int {
+	end := strings.IndexByte(text[i:], '\n')
+	if end < 0 {
+		return len(text)
+	}
+	return i + end + 1
+}
+
+// isCharLiteral reports whether a quote starts a character literal like
+// 'a' or '\n' and not a lifetime like 'a.
+func isCharLiteral(rest string) bool {
+	if len(rest) > 1 && rest[1] == '\\' {
+		return true
+	}
+	_, size := utf8.DecodeRuneInString(rest[1:])
+	return len(rest) > 1+size && rest[1+size] == '\''
+}
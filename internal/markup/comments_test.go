@@ -0,0 +1,77 @@
+package markup
+
+import (
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		language string
+		text     string
+		expect   string
+	}{
+		{
+			language: "go",
+			text: "// Package main is an example.\n//\n//go:generate stringer\npackage main\n\n" +
+				"var s = \"// no comment\" + `/* raw */` // a trailing comment\n\n" +
+				"/*\n * A block\n * comment.\n */\nfunc main() {}\n",
+			expect: " Package main is an example.\n\n\n\n a trailing comment\n\n\n\nA block\ncomment.\n\n\n",
+		},
+		{
+			language: "python",
+			text: "#!/usr/bin/env python\n# A comment.\ndef f():\n    \"\"\"A docstring.\n\n    More text.\n    \"\"\"\n" +
+				"    s = \"\"\"no docstring\"\"\"  # type: ignore\n    return '#'\n",
+			expect: "\n\n A comment.\n\n\nA docstring.\n\n    More text.\n    \n\n\n\n",
+		},
+		{
+			language: "typescript",
+			text:     "/**\n * Adds numbers.\n * @param {number} a the first one\n */\nconst s = `// ${a}`; // eslint-disable-line\n",
+			expect:   "\nAdds numbers.\n the first one\n\n\n",
+		},
+		{
+			language: "rust",
+			text:     "/// Returns a ref.\nfn f<'a>(s: &'a str) -> char { '\"' } /* nested /* comment */ text */\n//! Module docs.\n",
+			expect:   " Returns a ref.\n\n\n nested /* comment */ text \n\n Module docs.\n",
+		},
+		{
+			language: "java",
+			text:     "String s = \"\"\"\n    // text block\n    \"\"\"; // Ein Kommentar\n",
+			expect:   "\n\n Ein Kommentar\n",
+		},
+		{
+			language: "cpp",
+			text:     "#include <iostream> // For cout.\nchar c = '/'; /* Division. */\n",
+			expect:   "\n\n For cout.\n\n\n Division. \n\n",
+		},
+		{
+			language: "sh",
+			text:     "#!/bin/sh\n# Prints the count.\necho \"$# args # no comment\" '# raw' ${#x} # trailing\n",
+			expect:   "\n\n Prints the count.\n\n\n trailing\n",
+		},
+	}
+
+	for _, test := range tests {
+		text, ok := Extract(test.language, test.text, Options{})
+		if !ok {
+			t.Fatalf("%s: no extractor", test.language)
+		}
+		if whole := text.String(); whole != test.text {
+			t.Fatalf("%s: annotations are not lossless: %q", test.language, whole)
+		}
+		if prose := text.Prose(); prose != test.expect {
+			t.Errorf("%s: wrong prose want: %q, got: %q", test.language, test.expect, prose)
+		}
+	}
+}
+
+func FuzzComments(f *testing.F) {
+	f.Add("go", "// comment\nvar s = \"//\" /* block */\n")
+	f.Add("python", "def f():\n    \"\"\"doc\"\"\"  # comment\n")
+	f.Add("rust", "/* /* nested */ */ fn f<'a>() {}\n")
+	f.Fuzz(func(t *testing.T, language string, text string) {
+		extracted, ok := Extract(language, text, Options{})
+		if ok && extracted.String() != text {
+			t.Fatalf("annotations are not lossless want: %q, got: %q", text, extracted.String())
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+)
+
+var (
+	// gitScissors is the line git writes above the diff of
+	// `git commit --verbose`, everything below it is removed.
+	gitScissors = regexp.MustCompile(`^#? *-+ >8 -+ *$`)
+	gitTrailer  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*: `)
+)
+
+// GitCommit converts a commit message. Comments, the scissors line and
+// everything below it and the trailers of the last paragraph (e.g.
+// Signed-off-by:) are markup, only the subject and the body are checked.
+func GitCommit(text string) languagetool.AnnotatedText {
+	b := &builder{}
+	lines := splitLines(text)
+
+	end := len(lines)
+	for i, line := range lines {
+		if gitScissors.MatchString(trimNewline(line)) {
+			end = i
+			break
+		}
+	}
+
+	trailers := gitTrailers(lines[:end])
+	for i, line := range lines[:end] {
+		if isGitComment(line) || i >= trailers {
+			b.markup(line, "")
+			continue
+		}
+		b.text(line)
+	}
+	b.markup(strings.Join(lines[end:], ""), "")
+	return b.result()
+}
+
+// CommitSubject returns the byte offset and the text of the subject line
+// of a commit message, the first line which is neither blank nor a comment.
+func CommitSubject(text string) (int, string, bool) {
+	offset := 0
+	for _, line := range splitLines(text) {
+		content := trimNewline(line)
+		if gitScissors.MatchString(content) {
+			break
+		}
+		if !isGitComment(line) && strings.TrimSpace(content) != "" {
+			return offset, content, true
+		}
+		offset += len(line)
+	}
+	return 0, "", false
+}
+
+func isGitComment(line string) bool {
+	return strings.HasPrefix(line, "#")
+}
+
+// gitTrailers returns the index of the first line of the trailers, or the
+// number of lines if the last paragraph does not consist of trailers. Like
+// git only a paragraph after the subject can hold trailers, lines indented
+// with whitespace continue the trailer above.
+func gitTrailers(lines []string) int {
+	blank := func(line string) bool {
+		return strings.TrimSpace(line) == ""
+	}
+
+	end := len(lines)
+	for end > 0 && (blank(lines[end-1]) || isGitComment(lines[end-1])) {
+		end--
+	}
+	start := end
+	for start > 0 && !blank(lines[start-1]) {
+		start--
+	}
+
+	subject := false
+	for _, line := range lines[:start] {
+		subject = subject || !(blank(line) || isGitComment(line))
+	}
+	if !subject || start == end {
+		return len(lines)
+	}
+
+	trailer := false
+	for _, line := range lines[start:end] {
+		switch {
+		case isGitComment(line):
+		case gitTrailer.MatchString(line):
+			trailer = true
+		case trailer && (line[0] == ' ' || line[0] == '\t'):
+		default:
+			return len(lines)
+		}
+	}
+	return start
+}
@@ -0,0 +1,67 @@
+package markup
+
+import (
+	"testing"
+)
+
+func TestGitCommit(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		expect string
+	}{
+		{
+			name:   "subject and body",
+			text:   "Fix the parser\n\nThe parser skiped empty lines.\n",
+			expect: "Fix the parser\n\nThe parser skiped empty lines.\n",
+		},
+		{
+			name: "comments",
+			text: "Fix the parser\n\n# Please enter the commit message for your changes.\n" +
+				"# On branch main\n#\tmodified:   parser.go\n",
+			expect: "Fix the parser\n\n",
+		},
+		{
+			name: "scissors",
+			text: "Fix the parser\n\n# ------------------------ >8 ------------------------\n" +
+				"# Do not modify or remove the line above.\ndiff --git a/parser.go b/parser.go\n",
+			expect: "Fix the parser\n\n",
+		},
+		{
+			name: "trailers",
+			text: "Fix the parser\n\nThe body.\n\nSigned-off-by: Jane Doe <jane@example.com>\n" +
+				"Co-authored-by: John Doe\n  <john@example.com>\n# a comment\n",
+			expect: "Fix the parser\n\nThe body.\n\n",
+		},
+		{
+			name:   "the last paragraph is no trailer",
+			text:   "Fix the parser\n\nNote: this is prose.\nIt continues here.\n",
+			expect: "Fix the parser\n\nNote: this is prose.\nIt continues here.\n",
+		},
+		{
+			name:   "the subject is no trailer",
+			text:   "\nfix: the parser\n",
+			expect: "\nfix: the parser\n",
+		},
+	}
+
+	for _, test := range tests {
+		text := GitCommit(test.text)
+		if whole := text.String(); whole != test.text {
+			t.Fatalf("%s: annotations are not lossless: %q", test.name, whole)
+		}
+		if prose := text.Prose(); prose != test.expect {
+			t.Errorf("%s: wrong prose want: %q, got: %q", test.name, test.expect, prose)
+		}
+	}
+}
+
+func TestCommitSubject(t *testing.T) {
+	offset, subject, ok := CommitSubject("# comment\n\nFix the parser\n\nBody\n")
+	if !ok || offset != 11 || subject != "Fix the parser" {
+		t.Fatalf("wrong subject, got: %d %q %v", offset, subject, ok)
+	}
+	if _, _, ok := CommitSubject("# comment\n"); ok {
+		t.Fatal("a message without subject has a subject")
+	}
+}
@@ -0,0 +1,345 @@
+package markup
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+)
+
+// LaTeXOptions configures the commands and environments of LaTeX documents
+// which are skipped. Command names are given without the backslash.
+type LaTeXOptions struct {
+	// TextCommands are checked although they are skipped by default, e.g.
+	// "author". The arguments of unknown commands are always checked.
+	TextCommands []string `json:"textCommands,omitempty"`
+	// MarkupCommands are skipped with all of their arguments, e.g. "todo".
+	MarkupCommands []string `json:"markupCommands,omitempty"`
+	// MarkupEnvironments are skipped with their content, e.g. "tikzpicture".
+	MarkupEnvironments []string `json:"markupEnvironments,omitempty"`
+}
+
+// Merge combines the lists of both options.
+func (o LaTeXOptions) Merge(other LaTeXOptions) LaTeXOptions {
+	return LaTeXOptions{
+		TextCommands:       append(append([]string{}, o.TextCommands...), other.TextCommands...),
+		MarkupCommands:     append(append([]string{}, o.MarkupCommands...), other.MarkupCommands...),
+		MarkupEnvironments: append(append([]string{}, o.MarkupEnvironments...), other.MarkupEnvironments...),
+	}
+}
+
+// latexMarkupCommands are skipped with their arguments by default, mapped
+// to their interpretation. References and citations are part of the
+// sentence, so they are read as a word.
+var latexMarkupCommands = map[string]string{
+	"cite": codePlaceholder, "citep": codePlaceholder, "citet": codePlaceholder,
+	"parencite": codePlaceholder, "textcite": codePlaceholder, "autocite": codePlaceholder,
+	"ref": codePlaceholder, "eqref": codePlaceholder, "pageref": codePlaceholder,
+	"autoref": codePlaceholder, "cref": codePlaceholder, "Cref": codePlaceholder,
+	"nameref": codePlaceholder, "url": codePlaceholder,
+
+	"label": "", "input": "", "include": "", "includegraphics": "", "lstinputlisting": "",
+	"documentclass": "", "usepackage": "", "bibliography": "", "bibliographystyle": "",
+	"addbibresource": "", "graphicspath": "", "hypersetup": "",
+	"newcommand": "", "renewcommand": "", "providecommand": "", "newenvironment": "",
+	"renewenvironment": "", "newtheorem": "", "DeclareMathOperator": "",
+	"setlength": "", "addtolength": "", "setcounter": "", "vspace": "", "hspace": "",
+	"pagestyle": "", "thispagestyle": "", "author": "", "date": "", "texttt": codePlaceholder,
+}
+
+// latexMarkupEnvironments are skipped with their content by default, mapped
+// to their interpretation.
+var latexMarkupEnvironments = map[string]string{
+	"equation": codePlaceholder, "align": codePlaceholder, "gather": codePlaceholder,
+	"multline": codePlaceholder, "flalign": codePlaceholder, "alignat": codePlaceholder,
+	"eqnarray": codePlaceholder, "math": codePlaceholder, "displaymath": codePlaceholder,
+
+	"verbatim": paragraphBreak, "Verbatim": paragraphBreak, "lstlisting": paragraphBreak,
+	"minted": paragraphBreak, "comment": paragraphBreak,
+}
+
+// latexSymbols are the interpretations of escaped characters like \%.
+var latexSymbols = map[byte]string{
+	'%': "%", '&': "&", '$': "$", '#': "#", '_': "_", '{': "{", '}': "}",
+	' ': " ", ',': " ", ';': " ", ':': " ", '!': " ", '\\': "\n",
+}
+
+// latexAccents maps the accent commands like \"a to the composed letters.
+var latexAccents = map[byte]map[byte]string{
+	'"':  accentTable("aeiouyAEIOUY", "äëïöüÿÄËÏÖÜŸ"),
+	'\'': accentTable("aeiouycnszAEIOUYCNSZ", "áéíóúýćńśźÁÉÍÓÚÝĆŃŚŹ"),
+	'`':  accentTable("aeiouAEIOU", "àèìòùÀÈÌÒÙ"),
+	'^':  accentTable("aeiouAEIOU", "âêîôûÂÊÎÔÛ"),
+	'~':  accentTable("anoANO", "ãñõÃÑÕ"),
+	'=':  accentTable("aeiouAEIOU", "āēīōūĀĒĪŌŪ"),
+	'.':  accentTable("zecgZECGI", "żėċġŻĖĊĠİ"),
+}
+
+// latexLetterAccents are the accent commands named with a letter, like
+// \c{c} for ç and \v{s} for š.
+var latexLetterAccents = map[string]map[byte]string{
+	"c": accentTable("cCsStT", "çÇşŞţŢ"),
+	"v": accentTable("cCdDeEnNrRsStTzZ", "čČďĎěĚňŇřŘšŠťŤžŽ"),
+	"u": accentTable("aAgGuU", "ăĂğĞŭŬ"),
+	"H": accentTable("oOuU", "őŐűŰ"),
+}
+
+// latexLetters are the commands of letters without a key, like \ss for ß.
+var latexLetters = map[string]string{
+	"ss": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ",
+	"aa": "å", "AA": "Å", "o": "ø", "O": "Ø", "l": "ł", "L": "Ł",
+}
+
+func accentTable(letters string, composed string) map[byte]string {
+	result := map[byte]string{}
+	for i, r := range []rune(composed) {
+		result[letters[i]] = string(r)
+	}
+	return result
+}
+
+// LaTeX returns an Extractor for LaTeX documents. Commands, comments, math
+// and the environments and commands of the options are markup. The
+// arguments of other commands, e.g. \section{} and \emph{}, are checked.
+func LaTeX(options LaTeXOptions) Extractor {
+	commands := map[string]string{}
+	for name, interpretAs := range latexMarkupCommands {
+		commands[name] = interpretAs
+	}
+	for _, name := range options.MarkupCommands {
+		commands[strings.TrimPrefix(name, `\`)] = ""
+	}
+	for _, name := range options.TextCommands {
+		delete(commands, strings.TrimPrefix(name, `\`))
+	}
+
+	environments := map[string]string{}
+	for name, interpretAs := range latexMarkupEnvironments {
+		environments[name] = interpretAs
+		environments[name+"*"] = interpretAs
+	}
+	for _, name := range options.MarkupEnvironments {
+		environments[name] = paragraphBreak
+		environments[name+"*"] = paragraphBreak
+	}
+
+	return func(text string) languagetool.AnnotatedText {
+		l := &latex{source: text, commands: commands, environments: environments}
+		l.scan()
+		return l.result()
+	}
+}
+
+type latex struct {
+	builder
+	source       string
+	commands     map[string]string
+	environments map[string]string
+	// plain is the start of the text not added yet.
+	plain int
+}
+
+func (l *latex) scan() {
+	i := 0
+	for i < len(l.source) {
+		switch l.source[i] {
+		case '%':
+			i = l.markupUntil(i, lineEnd(l.source, i), "")
+		case '$':
+			delimiter := "$"
+			if strings.HasPrefix(l.source[i:], "$$") {
+				delimiter = "$$"
+			}
+			i = l.markupUntil(i, l.find(i+len(delimiter), delimiter), codePlaceholder)
+		case '~', '&':
+			i = l.markupUntil(i, i+1, " ")
+		case '{', '}':
+			i = l.markupUntil(i, i+1, "")
+		case '\\':
+			i = l.command(i)
+		default:
+			i++
+		}
+	}
+	l.text(l.source[l.plain:])
+}
+
+// markupUntil adds the text before start and the markup from start to end.
+func (l *latex) markupUntil(start int, end int, interpretAs string) int {
+	l.text(l.source[l.plain:start])
+	l.markup(l.source[start:end], interpretAs)
+	l.plain = end
+	return end
+}
+
+// command adds the command starting with the backslash at i.
+func (l *latex) command(i int) int {
+	if i+1 >= len(l.source) {
+		return l.markupUntil(i, i+1, "")
+	}
+
+	next := l.source[i+1]
+	if !isLatexLetter(next) {
+		switch next {
+		case '(':
+			return l.markupUntil(i, l.find(i+2, `\)`), codePlaceholder)
+		case '[':
+			return l.markupUntil(i, l.find(i+2, `\]`), codePlaceholder)
+		case '\\':
+			// a line break with an optional space like \\[2pt]
+			return l.markupUntil(i, l.arguments(i+2, "["), latexSymbols[next])
+		}
+		// accents like \"a are read as the composed letter
+		if letters, ok := latexAccents[next]; ok {
+			if letter, end, ok := l.accented(i+2, letters); ok {
+				return l.markupUntil(i, end, letter)
+			}
+		}
+		// other accents are skipped, the letter is checked
+		_, size := utf8.DecodeRuneInString(l.source[i+1:])
+		return l.markupUntil(i, i+1+size, latexSymbols[next])
+	}
+
+	end := i + 1
+	for end < len(l.source) && isLatexLetter(l.source[end]) {
+		end++
+	}
+	name := l.source[i+1 : end]
+	if end < len(l.source) && l.source[end] == '*' {
+		end++
+	}
+
+	switch name {
+	case "begin":
+		environment, argumentEnd := l.environment(end)
+		if interpretAs, ok := l.environments[environment]; ok {
+			return l.markupUntil(i, l.find(argumentEnd, `\end{`+environment+`}`), interpretAs)
+		}
+		return l.markupUntil(i, l.arguments(argumentEnd, "[{"), "")
+	case "end":
+		_, argumentEnd := l.environment(end)
+		return l.markupUntil(i, argumentEnd, "")
+	case "verb":
+		if end < len(l.source) {
+			return l.markupUntil(i, l.find(end+1, l.source[end:end+1]), codePlaceholder)
+		}
+	}
+
+	if letter, ok := latexLetters[name]; ok && end == i+1+len(name) {
+		// the braces of \ss{} are markup of their own
+		return l.markupUntil(i, end, letter)
+	}
+	if letters, ok := latexLetterAccents[name]; ok && end == i+1+len(name) {
+		// the letter follows the braces or a space, like \c{c} or \c c
+		start := end
+		if start < len(l.source) && l.source[start] == ' ' {
+			start++
+		}
+		if letter, accentEnd, ok := l.accented(start, letters); ok {
+			return l.markupUntil(i, accentEnd, letter)
+		}
+	}
+	if interpretAs, ok := l.commands[name]; ok {
+		return l.markupUntil(i, l.arguments(end, "[{"), interpretAs)
+	}
+	if name == "href" {
+		// the url is markup, the link text is checked
+		argumentEnd := l.arguments(end, "[")
+		if argumentEnd < len(l.source) && l.source[argumentEnd] == '{' {
+			argumentEnd = l.group(argumentEnd)
+		}
+		return l.markupUntil(i, argumentEnd, "")
+	}
+	// the braces of the arguments are markup, their content is checked
+	return l.markupUntil(i, l.arguments(end, "["), "")
+}
+
+// accented returns the composed letter of an accent command whose letter
+// starts at i, like a of \"a, \"{a} or \"\i, and the end of the command.
+func (l *latex) accented(i int, letters map[byte]string) (string, int, bool) {
+	end := i + 1
+	if i+2 < len(l.source) && l.source[i] == '{' && l.source[i+2] == '}' {
+		i, end = i+1, i+3
+	}
+	if i >= len(l.source) {
+		return "", 0, false
+	}
+	// the dotless \i and \j of \"\i
+	if rest := l.source[i:]; len(rest) >= 2 && rest[0] == '\\' && (rest[1] == 'i' || rest[1] == 'j') &&
+		(len(rest) == 2 || !isLatexLetter(rest[2])) {
+		letter, ok := letters[rest[1]]
+		return letter, i + 2, ok
+	}
+	letter, ok := letters[l.source[i]]
+	return letter, end, ok
+}
+
+// environment returns the name of the environment in the braces at i and
+// the end of the braces.
+func (l *latex) environment(i int) (string, int) {
+	if i >= len(l.source) || l.source[i] != '{' {
+		return "", i
+	}
+	end := strings.IndexByte(l.source[i:], '}')
+	if end < 0 {
+		return "", i
+	}
+	return l.source[i+1 : i+end], i + end + 1
+}
+
+// arguments returns the end of the arguments starting at i, which are
+// enclosed in one of the opening brackets.
+func (l *latex) arguments(i int, brackets string) int {
+	for i < len(l.source) && strings.IndexByte(brackets, l.source[i]) >= 0 {
+		i = l.group(i)
+	}
+	return i
+}
+
+// group returns the end of the group opened by the bracket at i, nested
+// braces are skipped.
+func (l *latex) group(i int) int {
+	closing := byte('}')
+	if l.source[i] == '[' {
+		closing = ']'
+	}
+
+	depth := 0
+	for j := i + 1; j < len(l.source); j++ {
+		switch l.source[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			if closing == '}' && depth == 0 {
+				return j + 1
+			}
+			depth--
+		case ']':
+			if closing == ']' && depth <= 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(l.source)
+}
+
+// find returns the end of the delimiter after i, skipping escaped
+// characters, or the end of the text if the delimiter is missing.
+func (l *latex) find(i int, delimiter string) int {
+	for i < len(l.source) {
+		if strings.HasPrefix(l.source[i:], delimiter) {
+			return i + len(delimiter)
+		}
+		if l.source[i] == '\\' && delimiter[0] != '\\' {
+			i++
+		}
+		i++
+	}
+	return len(l.source)
+}
+
+func isLatexLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '@'
+}
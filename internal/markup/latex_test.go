@@ -0,0 +1,86 @@
+package markup
+
+import (
+	"testing"
+)
+
+func TestLaTeX(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		options LaTeXOptions
+		expect  string
+	}{
+		{
+			name:   "sections and emphasis",
+			text:   "\\section{Einleitung}\nEin \\emph{wichtiger} Satz.\n",
+			expect: "Einleitung\nEin wichtiger Satz.\n",
+		},
+		{
+			name:   "comments and escapes",
+			text:   "Ein Satz. % ein Kommentar\n50\\% mehr \\& weniger.\n",
+			expect: "Ein Satz. 50% mehr & weniger.\n",
+		},
+		{
+			name:   "inline math",
+			text:   "Es gilt $a^2 + b^2 = c^2$ und \\(x \\leq y\\).\n",
+			expect: "Es gilt x und x.\n",
+		},
+		{
+			name:   "math environments",
+			text:   "Vorher\n\\begin{equation*}\n  E = mc^2\n\\end{equation*}\nnachher.\n",
+			expect: "Vorher\nx\nnachher.\n",
+		},
+		{
+			name:   "citations and references",
+			text:   "Wie in \\cite[S.~3]{knuth84} und Abbildung~\\ref{fig:a}\\label{sec:b}.\n",
+			expect: "Wie in x und Abbildung x.\n",
+		},
+		{
+			name:   "preamble and environments",
+			text:   "\\documentclass[a4paper]{article}\n\\usepackage{amsmath}\n\\begin{document}\n\\begin{itemize}\n  \\item Erster Punkt\n\\end{itemize}\n\\end{document}\n",
+			expect: "\n\n\n\n   Erster Punkt\n\n\n",
+		},
+		{
+			name:   "accents and letters",
+			text:   "K\\\"ase, Caf\\'e, \\\"{U}bung, na\\\"\\i{}v und Stra\\ss{}e.\n",
+			expect: "Käse, Café, Übung, naïv und Straße.\n",
+		},
+		{
+			name:   "letter accents",
+			text:   "Fran\\c{c}ais, \\v{S}koda und Gar\\c con.\n",
+			expect: "Français, Škoda und Garçon.\n",
+		},
+		{
+			name:   "links",
+			text:   "Siehe \\href{https://example.com/a_b}{die Dokumentation} und \\url{https://example.com}.\n",
+			expect: "Siehe die Dokumentation und x.\n",
+		},
+		{
+			name:    "configured commands",
+			text:    "\\author{Jane Doe}\n\\todo{Fehlr}\n\\begin{tikzpicture}\\node{A};\\end{tikzpicture}\n\\begin{tikzpicture*}\\node{B};\\end{tikzpicture*}\n",
+			options: LaTeXOptions{TextCommands: []string{"author"}, MarkupCommands: []string{"\\todo"}, MarkupEnvironments: []string{"tikzpicture"}},
+			expect:  "Jane Doe\n\n\n\n\n\n\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		text := LaTeX(test.options)(test.text)
+		if whole := text.String(); whole != test.text {
+			t.Fatalf("%s: annotations are not lossless: %q", test.name, whole)
+		}
+		if prose := text.Prose(); prose != test.expect {
+			t.Errorf("%s: wrong prose want: %q, got: %q", test.name, test.expect, prose)
+		}
+	}
+}
+
+func FuzzLaTeX(f *testing.F) {
+	f.Add("\\section{Titel} Text $x$ \\cite{a} % Kommentar\n\\begin{align}a\\end{align}")
+	f.Add("\\verb|x| \\\\[2pt] \\\"a {\\em y}")
+	f.Fuzz(func(t *testing.T, text string) {
+		if extracted := LaTeX(LaTeXOptions{})(text); extracted.String() != text {
+			t.Fatalf("annotations are not lossless want: %q, got: %q", text, extracted.String())
+		}
+	})
+}
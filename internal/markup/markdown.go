@@ -0,0 +1,298 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+)
+
+// paragraphBreak is the interpretation of markup separating paragraphs,
+// e.g. code blocks, so the text before and after it are not read as one
+// sentence.
+const paragraphBreak = "\n\n"
+
+// codePlaceholder is the interpretation of inline code, a short word keeps
+// the sentence around it intact.
+const codePlaceholder = "x"
+
+var (
+	markdownFence         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	markdownThematicBreak = regexp.MustCompile(`^ {0,3}(?:(?:- *){3,}|(?:\* *){3,}|(?:_ *){3,})$`)
+	markdownSetext        = regexp.MustCompile(`^ {0,3}(=+|-+) *$`)
+	markdownDefinition    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S+`)
+	markdownTableDivider  = regexp.MustCompile(`^ *\|? *:?-+:? *(\| *:?-+:? *)*\|? *$`)
+	markdownBlockPrefix   = regexp.MustCompile(`^( {0,3}> ?| {0,3}(?:[-+*]|\d{1,9}[.)])(?: +\[[ xX]\])?(?: +|$)| {0,3}#{1,6}(?: +|$))`)
+	markdownListItem      = regexp.MustCompile(`^ {0,3}(?:[-+*]|\d{1,9}[.)])(?: |$)`)
+	markdownTag           = regexp.MustCompile(`^</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+	markdownAutolink      = regexp.MustCompile(`^<[A-Za-z][A-Za-z0-9+.-]{1,31}:[^<>\s]*>|^<[^<>\s@]+@[^<>\s@]+>`)
+)
+
+// Markdown converts a Markdown document. Code blocks, inline code, front
+// matter, HTML and the syntax of links, emphasis, headings, lists, quotes
+// and tables are markup, only the prose is checked.
+func Markdown(text string) languagetool.AnnotatedText {
+	m := &markdown{}
+	lines := splitLines(text)
+
+	i := m.frontMatter(lines)
+	for ; i < len(lines); i++ {
+		m.line(lines[i])
+	}
+	return m.result()
+}
+
+type markdown struct {
+	builder
+	// fence is the opening fence of the current code block.
+	fence string
+	// comment is set inside of a HTML comment spanning several lines.
+	comment bool
+	// code is set inside of an indented code block.
+	code  bool
+	list  bool
+	blank bool
+}
+
+// frontMatter adds YAML front matter as markup and returns the index of the
+// first line after it.
+func (m *markdown) frontMatter(lines []string) int {
+	if len(lines) == 0 || trimNewline(lines[0]) != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if line := trimNewline(lines[i]); line == "---" || line == "..." {
+			m.markup(strings.Join(lines[:i+1], ""), paragraphBreak)
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (m *markdown) line(line string) {
+	content := trimNewline(line)
+	blank := strings.TrimSpace(content) == ""
+	defer func() { m.blank = blank }()
+
+	switch {
+	case m.fence != "":
+		m.markup(line, "")
+		if match := markdownFence.FindStringSubmatch(content); match != nil &&
+			match[1][0] == m.fence[0] && len(match[1]) >= len(m.fence) && strings.TrimSpace(content[len(match[0]):]) == "" {
+			m.fence = ""
+		}
+		return
+	case m.comment:
+		end := strings.Index(line, "-->")
+		if end < 0 {
+			m.markup(line, "")
+			return
+		}
+		m.comment = false
+		m.markup(line[:end+3], "")
+		m.inline(line[end+3:], false)
+		return
+	case blank:
+		m.text(line)
+		return
+	case (m.blank || m.code) && !m.list && indentation(content) >= 4:
+		interpretAs := ""
+		if !m.code {
+			interpretAs = paragraphBreak
+		}
+		m.code = true
+		m.markup(line, interpretAs)
+		return
+	}
+	m.code = false
+
+	if match := markdownFence.FindStringSubmatch(content); match != nil {
+		m.fence = match[1]
+		m.markup(line, paragraphBreak)
+		return
+	}
+	if markdownThematicBreak.MatchString(content) || markdownSetext.MatchString(content) ||
+		markdownDefinition.MatchString(content) || (strings.Contains(content, "|") && markdownTableDivider.MatchString(content)) {
+		m.markup(line, paragraphBreak)
+		return
+	}
+
+	if markdownListItem.MatchString(content) {
+		m.list = true
+	} else if indentation(content) == 0 && m.blank {
+		m.list = false
+	}
+
+	for {
+		prefix := markdownBlockPrefix.FindString(line)
+		if prefix == "" {
+			break
+		}
+		m.markup(prefix, "")
+		line = line[len(prefix):]
+	}
+	m.inline(line, strings.HasPrefix(strings.TrimSpace(line), "|"))
+}
+
+// inline adds the text of a line, table cells are separated like paragraphs.
+func (m *markdown) inline(line string, table bool) {
+	// the closing brackets of links and the end of their destinations
+	links := map[int]int{}
+	start := 0
+	flush := func(i int) {
+		if i > start {
+			m.text(line[start:i])
+		}
+	}
+	markup := func(i int, end int, interpretAs string) int {
+		flush(i)
+		m.markup(line[i:end], interpretAs)
+		start = end
+		return end
+	}
+
+	for i := 0; i < len(line); {
+		c := line[i]
+		if end, ok := links[i]; ok {
+			i = markup(i, end, "")
+			continue
+		}
+
+		switch {
+		case c == '\\' && i+1 < len(line) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", line[i+1]) >= 0:
+			markup(i, i+1, "")
+			i += 2
+			continue
+		case c == '`':
+			n := run(line, i)
+			if end := strings.Index(line[i+n:], line[i:i+n]); end >= 0 {
+				i = markup(i, i+n+end+n, codePlaceholder)
+				continue
+			}
+			i += n
+			continue
+		case strings.HasPrefix(line[i:], "<!--"):
+			end := strings.Index(line[i:], "-->")
+			if end < 0 {
+				markup(i, len(line), "")
+				m.comment = true
+				return
+			}
+			i = markup(i, i+end+3, "")
+			continue
+		case c == '<':
+			if tag := markdownAutolink.FindString(line[i:]); tag != "" {
+				i = markup(i, i+len(tag), "")
+				continue
+			}
+			if tag := markdownTag.FindString(line[i:]); tag != "" {
+				i = markup(i, i+len(tag), "")
+				continue
+			}
+		case c == '[' || (c == '!' && i+1 < len(line) && line[i+1] == '['):
+			open := i
+			if c == '!' {
+				open++
+			}
+			if closing, end, ok := link(line, open); ok {
+				links[closing] = end
+				i = markup(i, open+1, "")
+				continue
+			}
+		case c == '*' || c == '~':
+			n := run(line, i)
+			if c == '*' || n >= 2 {
+				i = markup(i, i+n, "")
+				continue
+			}
+			i += n
+			continue
+		case c == '_':
+			n := run(line, i)
+			before, _ := utf8.DecodeLastRuneInString(line[:i])
+			after, _ := utf8.DecodeRuneInString(line[i+n:])
+			// snake_case words are text
+			if !(isWord(before) && isWord(after)) {
+				i = markup(i, i+n, "")
+				continue
+			}
+			i += n
+			continue
+		case c == '|' && table:
+			i = markup(i, i+1, paragraphBreak)
+			continue
+		}
+		i++
+	}
+	flush(len(line))
+}
+
+// link reports whether the bracket at open starts a link or an image, it
+// returns the index of the closing bracket and the end of the destination
+// or reference.
+func link(line string, open int) (int, int, bool) {
+	closing := matching(line, open, '[', ']')
+	if closing < 0 || closing+1 >= len(line) {
+		return 0, 0, false
+	}
+	switch line[closing+1] {
+	case '(':
+		if end := matching(line, closing+1, '(', ')'); end >= 0 {
+			return closing, end + 1, true
+		}
+	case '[':
+		if end := matching(line, closing+1, '[', ']'); end >= 0 {
+			return closing, end + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// matching returns the index of the bracket closing the one at open.
+func matching(line string, open int, opening byte, closing byte) int {
+	depth := 0
+	for i := open; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case opening:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// run returns the number of repetitions of the byte at i.
+func run(line string, i int) int {
+	n := 1
+	for i+n < len(line) && line[i+n] == line[i] {
+		n++
+	}
+	return n
+}
+
+func indentation(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func isWord(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
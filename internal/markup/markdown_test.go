@@ -0,0 +1,84 @@
+package markup
+
+import (
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		expect string
+	}{
+		{
+			name:   "emphasis and inline code",
+			text:   "Ein **fetter** und *kursiver* Text mit `code` und snake_case.\n",
+			expect: "Ein fetter und kursiver Text mit x und snake_case.\n",
+		},
+		{
+			name:   "links and images",
+			text:   "Siehe [die Doku](https://example.com \"Titel\") und ![ein Bild](bild.png) oder [Referenz][ref].\n",
+			expect: "Siehe die Doku und ein Bild oder Referenz.\n",
+		},
+		{
+			name:   "headings, lists and quotes",
+			text:   "# Titel\n\n- [ ] Erster Punkt\n1. Zweiter Punkt\n> Ein Zitat\n",
+			expect: "Titel\n\nErster Punkt\nZweiter Punkt\nEin Zitat\n",
+		},
+		{
+			name:   "fenced code",
+			text:   "Vorher.\n\n```go\nfunc main() {}\n```\n\nNachher.\n",
+			expect: "Vorher.\n\n\n\n\nNachher.\n",
+		},
+		{
+			name:   "indented code",
+			text:   "Vorher.\n\n    x := 1\n    y := 2\n\nNachher.\n",
+			expect: "Vorher.\n\n\n\n\nNachher.\n",
+		},
+		{
+			name:   "front matter",
+			text:   "---\ntitle: Test\n---\nText.\n",
+			expect: "\n\nText.\n",
+		},
+		{
+			name:   "html",
+			text:   "Text <b>fett</b> <!-- ein\nKommentar --> und <https://example.com>.\n",
+			expect: "Text fett  und .\n",
+		},
+		{
+			name:   "tables",
+			text:   "| Name | Wert |\n| --- | :-: |\n| Eins | Zwei |\n",
+			expect: "\n\n Name \n\n Wert \n\n\n\n\n\n\n Eins \n\n Zwei \n\n\n",
+		},
+		{
+			name:   "escapes and thematic breaks",
+			text:   "Ein \\*Stern\\*.\n\n***\n\nCRLF Zeile\r\n",
+			expect: "Ein *Stern*.\n\n\n\n\nCRLF Zeile\r\n",
+		},
+		{
+			name:   "unclosed markup",
+			text:   "Ein `offener Code und [Link ohne Ziel].\n",
+			expect: "Ein `offener Code und [Link ohne Ziel].\n",
+		},
+	}
+
+	for _, test := range tests {
+		text := Markdown(test.text)
+		if whole := text.String(); whole != test.text {
+			t.Fatalf("%s: annotations are not lossless: %q", test.name, whole)
+		}
+		if prose := text.Prose(); prose != test.expect {
+			t.Fatalf("%s: wrong prose want: %q, got: %q\n%+v", test.name, test.expect, prose, text.Annotation)
+		}
+	}
+}
+
+func FuzzMarkdown(f *testing.F) {
+	f.Add("# Titel\n\nEin **Text** mit `code`, [Link](url) und <b>HTML</b>.\n\n```\ncode\n```\n")
+	f.Add("---\na: b\n---\n| a | b |\n|---|---|\n")
+	f.Fuzz(func(t *testing.T, text string) {
+		if whole := Markdown(text).String(); whole != text {
+			t.Fatalf("annotations are not lossless want: %q, got: %q", text, whole)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+// Package markup extracts the prose of documents like Markdown. Everything
+// else is passed to LanguageTool as markup, so it is skipped by the checks
+// while the offsets of the matches still refer to the whole document.
+package markup
+
+import (
+	"strings"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+)
+
+// Extractor converts a document into annotated text. Joining the
+// annotations results in the document again.
+type Extractor func(text string) languagetool.AnnotatedText
+
+// extractors by the language id of the documents.
+var extractors = map[string]Extractor{
+	"markdown":  Markdown,
+	"gitcommit": GitCommit,
+
+	"c":               comments(cSyntax),
+	"cpp":             comments(cSyntax),
+	"go":              comments(goSyntax),
+	"java":            comments(javaSyntax),
+	"javascript":      comments(javascriptSyntax),
+	"javascriptreact": comments(javascriptSyntax),
+	"typescript":      comments(javascriptSyntax),
+	"typescriptreact": comments(javascriptSyntax),
+	"rust":            comments(rustSyntax),
+	"python":          comments(pythonSyntax),
+	"shellscript":     comments(shellSyntax),
+	"sh":              comments(shellSyntax),
+	"bash":            comments(shellSyntax),
+	"zsh":             comments(shellSyntax),
+}
+
+// configurable are the extractors depending on the options by the language
+// id of the documents.
+var configurable = map[string]func(options Options) Extractor{
+	"latex": func(options Options) Extractor { return LaTeX(options.LaTeX) },
+	"tex":   func(options Options) Extractor { return LaTeX(options.LaTeX) },
+}
+
+// Options configures the extractors.
+type Options struct {
+	LaTeX LaTeXOptions `json:"latex"`
+}
+
+// Merge combines both options.
+func (o Options) Merge(other Options) Options {
+	return Options{LaTeX: o.LaTeX.Merge(other.LaTeX)}
+}
+
+// Extract returns the annotated text of a document. It reports false for
+// languages without an extractor, they are checked as plain text.
+func Extract(languageID string, text string, options Options) (languagetool.AnnotatedText, bool) {
+	if extractor, ok := configurable[languageID]; ok {
+		return extractor(options)(text), true
+	}
+	extract, ok := extractors[languageID]
+	if !ok {
+		return languagetool.AnnotatedText{}, false
+	}
+	return extract(text), true
+}
+
+// builder collects the annotations of a document, adjacent annotations of
+// the same kind are merged.
+type builder struct {
+	annotations []languagetool.Annotation
+}
+
+func (b *builder) text(text string) {
+	if text == "" {
+		return
+	}
+	if last := b.last(); last != nil && last.Markup == "" {
+		last.Text += text
+		return
+	}
+	b.annotations = append(b.annotations, languagetool.Annotation{Text: text})
+}
+
+// markup adds markup, which LanguageTool reads as interpretAs.
+func (b *builder) markup(markup string, interpretAs string) {
+	if markup == "" {
+		return
+	}
+	if last := b.last(); last != nil && last.Markup != "" {
+		last.Markup += markup
+		last.InterpretAs += interpretAs
+		return
+	}
+	b.annotations = append(b.annotations, languagetool.Annotation{Markup: markup, InterpretAs: interpretAs})
+}
+
+func (b *builder) last() *languagetool.Annotation {
+	if len(b.annotations) == 0 {
+		return nil
+	}
+	return &b.annotations[len(b.annotations)-1]
+}
+
+func (b *builder) result() languagetool.AnnotatedText {
+	if b.annotations == nil {
+		return languagetool.AnnotatedText{Annotation: []languagetool.Annotation{}}
+	}
+	return languagetool.AnnotatedText{Annotation: b.annotations}
+}
+
+// splitLines splits text after each line break.
+func splitLines(text string) []string {
+	return strings.SplitAfter(text, "\n")
+}
+
+// trimNewline removes the line break at the end of a line.
+func trimNewline(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.lsp.dev/uri"
+)
+
+const (
+	// checkDocumentCommand checks a document now, see documentArguments.
+	checkDocumentCommand = "languagetool.checkDocument"
+	// checkWorkspaceCommand checks all open documents now.
+	checkWorkspaceCommand = "languagetool.checkWorkspace"
+	// setLanguageCommand sets the language of the checks, see
+	// setLanguageArguments.
+	setLanguageCommand = "languagetool.setLanguage"
+	// clearCacheCommand drops the cached results and checks all open
+	// documents again.
+	clearCacheCommand = "languagetool.clearCache"
+)
+
+// command can be executed by the editor with workspace/executeCommand. The
+// only argument of a command is a JSON object decoded into its arguments.
+type command struct {
+	name string
+	run  func(ctx context.Context, s Server, arguments []interface{}) error
+}
+
+// commands are the commands the server advertises to the editor.
+var commands = []command{
+	newCommand(checkDocumentCommand, func(ctx context.Context, s Server, arguments documentArguments) error {
+		return s.checkNow(arguments.URI)
+	}),
+	newCommand(checkWorkspaceCommand, func(ctx context.Context, s Server, arguments struct{}) error {
+		s.recheck()
+		return nil
+	}),
+	newCommand(addWordCommand, func(ctx context.Context, s Server, arguments addWordArguments) error {
+		if arguments.Folder != "" {
+			return s.addWorkspaceWord(arguments.Word, arguments.Folder)
+		}
+		return s.addWord(ctx, arguments.Word)
+	}),
+	newCommand(ignoreCommand, func(ctx context.Context, s Server, arguments ignoredMatch) error {
+		return s.ignore(arguments)
+	}),
+	newCommand(disableRuleCommand, func(ctx context.Context, s Server, arguments disableRuleArguments) error {
+		return s.disableRule(arguments)
+	}),
+	newCommand(setLanguageCommand, func(ctx context.Context, s Server, arguments setLanguageArguments) error {
+		return s.setLanguage(arguments)
+	}),
+	newCommand(clearCacheCommand, func(ctx context.Context, s Server, arguments struct{}) error {
+		return s.clearCache()
+	}),
+}
+
+// newCommand returns a command decoding its argument into T. Commands
+// without arguments use struct{}.
+func newCommand[T any](name string, run func(ctx context.Context, s Server, arguments T) error) command {
+	return command{
+		name: name,
+		run: func(ctx context.Context, s Server, arguments []interface{}) error {
+			var decoded T
+			if err := decodeArgument(name, arguments, &decoded); err != nil {
+				return err
+			}
+			return run(ctx, s, decoded)
+		},
+	}
+}
+
+// decodeArgument decodes the only argument of a command, a missing argument
+// leaves v unchanged.
+func decodeArgument(command string, arguments []interface{}, v interface{}) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("%s expects one argument, got: %d", command, len(arguments))
+	}
+	raw, err := json.Marshal(arguments[0])
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("invalid argument of %s: %w", command, err)
+	}
+	return nil
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, command.name)
+	}
+	return names
+}
+
+type documentArguments struct {
+	URI uri.URI `json:"uri"`
+}
+
+type setLanguageArguments struct {
+	// Language is a language code like de-DE or auto.
+	Language string `json:"language"`
+	// URI is the document to set the language of, without it the language
+	// is set for all documents.
+	URI uri.URI `json:"uri,omitempty"`
+}
+
+// cache is implemented by the languagetool.Cache.
+type cache interface {
+	Clear() error
+}
+
+// checkNow checks a document without waiting for the debounce delay.
+func (s Server) checkNow(document uri.URI) error {
+	if _, ok := s.documents.get(document); !ok {
+		return fmt.Errorf("unknown document %s", document)
+	}
+	s.scheduler.schedule(document, 0)
+	return nil
+}
+
+func (s Server) setLanguage(arguments setLanguageArguments) error {
+	if arguments.Language == "" {
+		return errors.New("missing language")
+	}
+	if arguments.URI == "" {
+		s.config.setLanguage(arguments.Language)
+		s.recheck()
+		return nil
+	}
+	if !s.documents.setLanguage(arguments.URI, arguments.Language) {
+		return fmt.Errorf("unknown document %s", arguments.URI)
+	}
+	s.scheduler.schedule(arguments.URI, 0)
+	return nil
+}
+
+func (s Server) clearCache() error {
+	cache, ok := s.languagetool.(cache)
+	if !ok {
+		return errors.New("the cache is disabled")
+	}
+	if err := cache.Clear(); err != nil {
+		return err
+	}
+	s.recheck()
+	return nil
+}
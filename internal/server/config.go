@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pascal-sochacki/languagetool-lsp/internal/markup"
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+)
+
+// Config of the server. The user configuration is passed to NewServer, the
+// workspace configuration is sent by the editor as initialization options
+// or with workspace/didChangeConfiguration.
+type Config struct {
+	Check     languagetool.CheckOptions `json:"check"`
+	Severity  SeverityConfig            `json:"severity"`
+	GitCommit GitCommitConfig           `json:"gitCommit"`
+	Markup    markup.Options            `json:"markup"`
+	// Debounce is the time to wait after a change before the document is
+	// checked. It is only read from the user configuration.
+	Debounce time.Duration `json:"-"`
+	// Dictionary is the file of the user dictionary, without it added
+	// words are forgotten on exit. It is only read from the user
+	// configuration.
+	Dictionary string `json:"-"`
+	// DisabledRulesFile keeps the rules disabled globally with a code
+	// action. It is only read from the user configuration.
+	DisabledRulesFile string `json:"-"`
+	// DocumentRulesFile keeps the rules disabled and the matches ignored
+	// in documents outside of workspace folders. It is only read from the
+	// user configuration.
+	DocumentRulesFile string `json:"-"`
+	// Words optionally adds words to the dictionary of the LanguageTool
+	// account as well.
+	Words languagetool.WordsApi `json:"-"`
+}
+
+func (c Config) merge(other Config) Config {
+	c.Check = c.Check.Merge(other.Check)
+	c.Severity = c.Severity.merge(other.Severity)
+	c.GitCommit = c.GitCommit.merge(other.GitCommit)
+	c.Markup = c.Markup.Merge(other.Markup)
+	return c
+}
+
+type configuration struct {
+	mu        sync.RWMutex
+	user      Config
+	workspace Config
+	// session is changed with commands until the server exits.
+	session Config
+}
+
+func newConfiguration(user Config) *configuration {
+	return &configuration{user: user}
+}
+
+func (c *configuration) get() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.user.merge(c.workspace).merge(c.session)
+}
+
+func (c *configuration) setWorkspace(workspace Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.workspace = workspace
+}
+
+func (c *configuration) setLanguage(language string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session.Check.Language = language
+}
+
+// parseSettings decodes the settings sent by the editor. They are either
+// nested under a "languagetool" key or are the config itself.
+func parseSettings(settings interface{}) (Config, error) {
+	config := Config{}
+	if settings == nil {
+		return config, nil
+	}
+
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return config, err
+	}
+
+	nested := struct {
+		Languagetool *Config `json:"languagetool"`
+	}{}
+	if err := json.Unmarshal(raw, &nested); err == nil && nested.Languagetool != nil {
+		return *nested.Languagetool, nil
+	}
+
+	err = json.Unmarshal(raw, &config)
+	return config, err
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// Data is attached to every diagnostic, the editor sends it back with the
+// diagnostics of a code action request.
+type Data struct {
+	Replacements []string `json:"replacements"`
+	// Word is the unknown word of a spelling mistake.
+	Word string `json:"word,omitempty"`
+	// Rule, Text and Sentence identify the match to ignore it.
+	Rule     string `json:"rule,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Sentence string `json:"sentence,omitempty"`
+}
+
+// decodeData decodes the data of a diagnostic, which the editor sends as
+// plain JSON.
+func decodeData(value interface{}) (Data, bool) {
+	data := Data{}
+	if value == nil {
+		return data, false
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return data, false
+	}
+	return data, json.Unmarshal(raw, &data) == nil
+}
+
+// matchText returns the text of the document flagged by a match.
+func matchText(doc document, mapper *positionMapper, match languagetool.Match) string {
+	return doc.Text[mapper.byteOffset(match.Offset):mapper.byteOffset(match.Offset+match.Length)]
+}
+
+// newDiagnostic converts a match of LanguageTool into a diagnostic of the
+// document.
+func newDiagnostic(doc document, mapper *positionMapper, match languagetool.Match, language languagetool.Language, config Config) protocol.Diagnostic {
+	replacements := []string{}
+	for _, v := range match.Replacements {
+		replacements = append(replacements, v.Value)
+	}
+
+	data := Data{
+		Replacements: replacements,
+		Rule:         match.Rule.ID,
+		Text:         matchText(doc, mapper, match),
+		Sentence:     match.Sentence,
+	}
+	if isMisspelling(match) {
+		data.Word = data.Text
+	}
+
+	diagnostic := protocol.Diagnostic{
+		Message:  fmt.Sprintf("%s (%s)", match.Message, strings.Join(replacements, ", ")),
+		Data:     data,
+		Range:    mapper.rangeOf(match.Offset, match.Length),
+		Severity: config.Severity.severity(match),
+		Source:   "languagetool",
+	}
+
+	if match.Rule.ID != "" {
+		diagnostic.Code = match.Rule.ID
+		diagnostic.CodeDescription = &protocol.CodeDescription{Href: ruleURL(match.Rule, language)}
+	}
+
+	if match.Sentence != "" {
+		diagnostic.RelatedInformation = []protocol.DiagnosticRelatedInformation{
+			{
+				Location: protocol.Location{
+					URI:   doc.URI,
+					Range: sentenceRange(doc, mapper, match, diagnostic.Range),
+				},
+				Message: match.Sentence,
+			},
+		}
+	}
+	return diagnostic
+}
+
+// ruleURL returns the documentation of the rule or its page on the
+// LanguageTool community website.
+func ruleURL(rule languagetool.Rule, language languagetool.Language) uri.URI {
+	if len(rule.URLs) > 0 && rule.URLs[0].Value != "" {
+		return uri.URI(rule.URLs[0].Value)
+	}
+
+	query := url.Values{}
+	if language.Code != "" {
+		query.Set("lang", language.Code)
+	}
+	if rule.SubID != "" {
+		query.Set("subId", rule.SubID)
+	}
+	href := "https://community.languagetool.org/rule/show/" + url.PathEscape(rule.ID)
+	if len(query) > 0 {
+		href += "?" + query.Encode()
+	}
+	return uri.URI(href)
+}
+
+// sentenceRange finds the sentence of the match around it in the document.
+// LanguageTool may normalize the sentence, the range of the match is used
+// if it can't be found.
+func sentenceRange(doc document, mapper *positionMapper, match languagetool.Match, fallback protocol.Range) protocol.Range {
+	start := mapper.byteOffset(match.Offset)
+
+	from := start - len(match.Sentence)
+	if from < 0 {
+		from = 0
+	}
+	for {
+		i := strings.Index(doc.Text[from:], match.Sentence)
+		if i < 0 || from+i > start {
+			return fallback
+		}
+		if from+i+len(match.Sentence) >= start {
+			return protocol.Range{
+				Start: mapper.position(from + i),
+				End:   mapper.position(from + i + len(match.Sentence)),
+			}
+		}
+		from += i + 1
+	}
+}
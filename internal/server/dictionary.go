@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/uri"
+)
+
+// addWordCommand adds a word to a dictionary, see addWordArguments.
+const addWordCommand = "languagetool.addWord"
+
+type addWordArguments struct {
+	Word string `json:"word"`
+	// Folder is the workspace folder of the dictionary, the user dictionary
+	// is used without it.
+	Folder uri.URI `json:"folder,omitempty"`
+}
+
+// DefaultDictionaryFile returns the user dictionary in the XDG config
+// directory.
+func DefaultDictionaryFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lt-lsp", "dictionary.txt"), nil
+}
+
+// wordList is a dictionary file with one word per line, empty lines and
+// lines starting with # are ignored. Without a path the words are only kept
+// in memory.
+type wordList struct {
+	mu    sync.RWMutex
+	path  string
+	words map[string]struct{}
+}
+
+func newWordList(path string) *wordList {
+	return &wordList{path: path, words: map[string]struct{}{}}
+}
+
+func (w *wordList) file() string {
+	return w.path
+}
+
+// load reads the words of the file, a missing file is an empty dictionary.
+func (w *wordList) load() error {
+	words := map[string]struct{}{}
+	if w.path != "" {
+		file, err := os.Open(w.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err == nil {
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				word := strings.TrimSpace(scanner.Text())
+				if word == "" || strings.HasPrefix(word, "#") {
+					continue
+				}
+				words[word] = struct{}{}
+			}
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.words = words
+	return nil
+}
+
+func (w *wordList) contains(word string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, ok := w.words[word]
+	return ok
+}
+
+// list returns the words sorted.
+func (w *wordList) list() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	words := make([]string, 0, len(w.words))
+	for word := range w.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// add appends a word to the file, the file and its directory are created if
+// needed.
+func (w *wordList) add(word string) error {
+	word = strings.TrimSpace(word)
+	if word == "" || strings.ContainsAny(word, "\r\n") {
+		return fmt.Errorf("invalid word %q", word)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.words[word]; ok {
+		return nil
+	}
+	if w.path != "" {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(file, word); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	w.words[word] = struct{}{}
+	return nil
+}
+
+// isMisspelling reports whether a match is an unknown word, which can be
+// added to a dictionary. Not every speller rule sets the type of the match,
+// so the rules of the TYPOS category are unknown words as well.
+func isMisspelling(match languagetool.Match) bool {
+	return match.Type.TypeName == "UnknownWord" ||
+		match.Rule.IssueType == "misspelling" && match.Rule.Category.ID == "TYPOS"
+}
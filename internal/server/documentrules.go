@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.lsp.dev/uri"
+)
+
+// documentRulesName is the file of the choices for the documents of a
+// workspace folder.
+const documentRulesName = "documents.json"
+
+// DefaultDocumentRulesFile returns the file of the choices for documents
+// outside of workspace folders in the XDG config directory.
+func DefaultDocumentRulesFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lt-lsp", documentRulesName), nil
+}
+
+// documentRules are the rules disabled and the matches ignored in a single
+// document.
+type documentRules struct {
+	DisabledRules []string       `json:"disabledRules,omitempty"`
+	Ignored       []ignoredMatch `json:"ignored,omitempty"`
+}
+
+// documentRulesFile keeps the documentRules of documents by their path.
+// Without a path they are only kept in memory.
+type documentRulesFile struct {
+	mu        sync.RWMutex
+	path      string
+	documents map[string]documentRules
+}
+
+func newDocumentRulesFile(path string) *documentRulesFile {
+	return &documentRulesFile{path: path, documents: map[string]documentRules{}}
+}
+
+func (d *documentRulesFile) file() string {
+	return d.path
+}
+
+// load reads the file, a missing file has no rules.
+func (d *documentRulesFile) load() error {
+	documents := map[string]documentRules{}
+	if d.path != "" {
+		data, err := os.ReadFile(d.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &documents); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.documents = documents
+	return nil
+}
+
+func (d *documentRulesFile) get(document string) documentRules {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.documents[document]
+}
+
+func (d *documentRulesFile) disableRule(document string, rule string) error {
+	return d.update(document, func(rules *documentRules) {
+		for _, disabled := range rules.DisabledRules {
+			if disabled == rule {
+				return
+			}
+		}
+		rules.DisabledRules = append(rules.DisabledRules, rule)
+	})
+}
+
+func (d *documentRulesFile) ignore(document string, match ignoredMatch) error {
+	// the document is the key already
+	match.URI = ""
+	return d.update(document, func(rules *documentRules) {
+		for _, ignored := range rules.Ignored {
+			if ignored == match {
+				return
+			}
+		}
+		rules.Ignored = append(rules.Ignored, match)
+	})
+}
+
+// update changes the rules of a document and writes the file, the file and
+// its directory are created if needed.
+func (d *documentRulesFile) update(document string, change func(rules *documentRules)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rules := d.documents[document]
+	change(&rules)
+	documents := make(map[string]documentRules, len(d.documents)+1)
+	for key, value := range d.documents {
+		documents[key] = value
+	}
+	documents[document] = rules
+
+	if d.path != "" {
+		data, err := json.MarshalIndent(documents, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(d.path, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	d.documents = documents
+	return nil
+}
+
+// documentRules returns the file keeping the rules of a document and the
+// key of the document in it. Documents of a workspace folder are stored in
+// the folder by their relative path, other files in the config directory.
+// Documents without a path are only kept until the server exits.
+func (s Server) documentRules(document uri.URI) (*documentRulesFile, string) {
+	path, ok := filename(document)
+	if !ok {
+		return s.sessionRules, string(document)
+	}
+	if folder, ok := s.workspaces.folder(document); ok {
+		if relative, err := filepath.Rel(folder.root, path); err == nil {
+			return folder.documents, filepath.ToSlash(relative)
+		}
+	}
+	return s.globalDocuments, path
+}
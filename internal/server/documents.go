@@ -0,0 +1,156 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+type document struct {
+	URI         uri.URI
+	Text        string
+	Version     int32
+	LanguageID  protocol.LanguageIdentifier
+	Diagnostics []protocol.Diagnostic
+	// Matches are the matches of LanguageTool the diagnostics were built
+	// from, in the same order.
+	Matches []languagetool.Match
+	// DiagnosticsVersion is the version of the text the diagnostics were
+	// computed for. Their ranges do not fit a newer text.
+	DiagnosticsVersion int32
+	// Language overwrites the configured language for this document.
+	Language string
+}
+
+// documentStore keeps the documents opened in the editor, it is safe for
+// concurrent use. All methods return copies of the documents.
+type documentStore struct {
+	mu        sync.RWMutex
+	documents map[uri.URI]*document
+	encoding  positionEncoding
+}
+
+func newDocumentStore() *documentStore {
+	return &documentStore{
+		documents: map[uri.URI]*document{},
+		encoding:  encodingUTF16,
+	}
+}
+
+// setEncoding sets the encoding of the positions the client sends.
+func (d *documentStore) setEncoding(encoding positionEncoding) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.encoding = encoding
+}
+
+// mapper returns a positionMapper for the text of a document.
+func (d *documentStore) mapper(doc document) *positionMapper {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return newPositionMapper(doc.Text, d.encoding)
+}
+
+func (d *documentStore) open(item protocol.TextDocumentItem) document {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	doc := &document{
+		URI:        item.URI,
+		Text:       item.Text,
+		Version:    item.Version,
+		LanguageID: item.LanguageID,
+	}
+	d.documents[item.URI] = doc
+	return *doc
+}
+
+// change applies the changes to the text of a document, a document which
+// was not opened before is added.
+func (d *documentStore) change(uri uri.URI, version int32, changes []contentChange) (document, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	doc, ok := d.documents[uri]
+	if !ok {
+		doc = &document{URI: uri}
+		d.documents[uri] = doc
+	}
+	text, err := applyChanges(doc.Text, changes, d.encoding)
+	if err != nil {
+		return *doc, err
+	}
+	doc.Text = text
+	doc.Version = version
+	return *doc, nil
+}
+
+func (d *documentStore) close(uri uri.URI) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.documents, uri)
+}
+
+func (d *documentStore) get(uri uri.URI) (document, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	doc, ok := d.documents[uri]
+	if !ok {
+		return document{}, false
+	}
+	return *doc, true
+}
+
+func (d *documentStore) all() []document {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	docs := make([]document, 0, len(d.documents))
+	for _, doc := range d.documents {
+		docs = append(docs, *doc)
+	}
+	return docs
+}
+
+// setDiagnostics stores the diagnostics of a document and the matches they
+// were built from. They are dropped if the document was closed or changed
+// since it was checked.
+func (d *documentStore) setDiagnostics(uri uri.URI, version int32, diagnostics []protocol.Diagnostic, matches []languagetool.Match) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	doc, ok := d.documents[uri]
+	if !ok || doc.Version != version {
+		return false
+	}
+	doc.Diagnostics = diagnostics
+	doc.Matches = matches
+	doc.DiagnosticsVersion = version
+	return true
+}
+
+// currentDiagnostics returns the diagnostics and matches of a document if
+// they were computed for its current text, otherwise none until the
+// document is checked again.
+func (d document) currentDiagnostics() ([]protocol.Diagnostic, []languagetool.Match) {
+	if d.DiagnosticsVersion != d.Version {
+		return nil, nil
+	}
+	return d.Diagnostics, d.Matches
+}
+
+// setLanguage sets the language of a document until it is closed.
+func (d *documentStore) setLanguage(uri uri.URI, language string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	doc, ok := d.documents[uri]
+	if !ok {
+		return false
+	}
+	doc.Language = language
+	return true
+}
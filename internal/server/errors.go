@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+// errorReporter shows failed checks to the user. The same message is shown
+// only once until a check succeeds again, so typing while offline does not
+// open a new message for every keystroke.
+type errorReporter struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (r *errorReporter) report(ctx context.Context, log *zap.Logger, client protocol.Client, err error) {
+	message := errorMessage(err)
+
+	r.mu.Lock()
+	if r.last == message {
+		r.mu.Unlock()
+		return
+	}
+	r.last = message
+	r.mu.Unlock()
+
+	if err := client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type:    protocol.MessageTypeError,
+		Message: message,
+	}); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+func (r *errorReporter) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = ""
+}
+
+func errorMessage(err error) string {
+	switch {
+	case errors.Is(err, languagetool.ErrUnauthorized):
+		return "LanguageTool: invalid username or api key, run `lt-lsp configure <username> <api-token>`"
+	case errors.Is(err, languagetool.ErrRateLimited):
+		if retryAfter, ok := languagetool.RetryAfter(err); ok {
+			return fmt.Sprintf("LanguageTool: rate limit exceeded, try again in %s", retryAfter)
+		}
+		return "LanguageTool: rate limit exceeded"
+	case errors.Is(err, languagetool.ErrPayloadTooLarge):
+		return "LanguageTool: the document is too large to be checked"
+	case errors.Is(err, languagetool.ErrServer):
+		return "LanguageTool: the server failed to check the document"
+	default:
+		return fmt.Sprintf("LanguageTool: check failed: %s", err)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// sourceFixAll applies every unambiguous correction of a document.
+const sourceFixAll protocol.CodeActionKind = "source.fixAll.languagetool"
+
+// fixAllActions returns the actions applying the only replacement of every
+// diagnostic: one for the whole document and one for a selected range.
+func fixAllActions(doc document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+
+	if kindRequested(params.Context.Only, sourceFixAll) {
+		if edits := fixAllEdits(doc, nil); len(edits) > 0 {
+			actions = append(actions, protocol.CodeAction{
+				Title: "Fix all unambiguous LanguageTool issues",
+				Kind:  sourceFixAll,
+				Edit:  versionedEdit(doc, edits),
+			})
+		}
+	}
+
+	if params.Range.Start != params.Range.End && kindRequested(params.Context.Only, protocol.QuickFix) {
+		if edits := fixAllEdits(doc, &params.Range); len(edits) > 0 {
+			actions = append(actions, protocol.CodeAction{
+				Title: "Fix all unambiguous LanguageTool issues in selection",
+				Kind:  protocol.QuickFix,
+				Edit:  versionedEdit(doc, edits),
+			})
+		}
+	}
+	return actions
+}
+
+// fixAllEdits returns non-overlapping edits for the diagnostics with exactly
+// one replacement, optionally only those within a range. Of overlapping
+// diagnostics the first one wins. There are none while the diagnostics are
+// older than the text.
+func fixAllEdits(doc document, within *protocol.Range) []protocol.TextEdit {
+	diagnostics, matches := doc.currentDiagnostics()
+	edits := []protocol.TextEdit{}
+	for i, diagnostic := range diagnostics {
+		if i >= len(matches) || len(matches[i].Replacements) != 1 {
+			continue
+		}
+		if within != nil && (before(diagnostic.Range.Start, within.Start) || before(within.End, diagnostic.Range.End)) {
+			continue
+		}
+		edits = append(edits, protocol.TextEdit{
+			Range:   diagnostic.Range,
+			NewText: matches[i].Replacements[0].Value,
+		})
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		return before(edits[i].Range.Start, edits[j].Range.Start)
+	})
+	result := []protocol.TextEdit{}
+	for _, edit := range edits {
+		if len(result) > 0 && before(edit.Range.Start, result[len(result)-1].Range.End) {
+			continue
+		}
+		result = append(result, edit)
+	}
+	return result
+}
+
+// versionedEdit returns an edit the editor only applies to the version of
+// the document the diagnostics were computed for.
+func versionedEdit(doc document, edits []protocol.TextEdit) *protocol.WorkspaceEdit {
+	version := doc.DiagnosticsVersion
+	return &protocol.WorkspaceEdit{
+		DocumentChanges: []protocol.TextDocumentEdit{
+			{
+				TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: doc.URI},
+					Version:                &version,
+				},
+				Edits: edits,
+			},
+		},
+	}
+}
+
+// kindRequested reports whether actions of a kind were requested, an empty
+// list requests all kinds.
+func kindRequested(only []protocol.CodeActionKind, kind protocol.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, requested := range only {
+		if kind == requested || strings.HasPrefix(string(kind), string(requested)+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/pascal-sochacki/languagetool-lsp/internal/markup"
+	"go.lsp.dev/protocol"
+)
+
+// gitCommitLanguage is the language id of commit messages.
+const gitCommitLanguage = "gitcommit"
+
+// subjectLengthCode is the code of the diagnostic of a long subject line.
+const subjectLengthCode = "SUBJECT_LENGTH"
+
+// GitCommitConfig configures the checks of commit messages.
+type GitCommitConfig struct {
+	// SubjectLength is the maximum number of characters of the subject
+	// line, longer ones are flagged. Unset or 0 disables the check, so a
+	// workspace can disable the check of the user config.
+	SubjectLength *int `json:"subjectLength,omitempty"`
+}
+
+func (c GitCommitConfig) merge(other GitCommitConfig) GitCommitConfig {
+	if other.SubjectLength != nil {
+		c.SubjectLength = other.SubjectLength
+	}
+	return c
+}
+
+// subjectDiagnostics returns a diagnostic for the characters of the subject
+// line of a commit message exceeding the configured length. It is a warning
+// unless the severity of SUBJECT_LENGTH is configured.
+func subjectDiagnostics(doc document, mapper *positionMapper, config Config) []protocol.Diagnostic {
+	if string(doc.LanguageID) != gitCommitLanguage || config.GitCommit.SubjectLength == nil {
+		return nil
+	}
+	length := *config.GitCommit.SubjectLength
+	offset, subject, ok := markup.CommitSubject(doc.Text)
+	if length <= 0 || !ok || utf8.RuneCountInString(subject) <= length {
+		return nil
+	}
+
+	cut := 0
+	for i := 0; i < length; i++ {
+		_, size := utf8.DecodeRuneInString(subject[cut:])
+		cut += size
+	}
+	severity, ok := lookupSeverity(config.Severity.Rules, subjectLengthCode)
+	if !ok {
+		severity = protocol.DiagnosticSeverityWarning
+	}
+	return []protocol.Diagnostic{
+		{
+			Message: fmt.Sprintf("The subject line is longer than %d characters (%d)",
+				length, utf8.RuneCountInString(subject)),
+			Range: protocol.Range{
+				Start: mapper.position(offset + cut),
+				End:   mapper.position(offset + len(subject)),
+			},
+			Severity: severity,
+			Source:   "languagetool",
+			Code:     subjectLengthCode,
+		},
+	}
+}
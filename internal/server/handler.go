@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+type didChangeParams struct {
+	TextDocument   protocol.VersionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange                          `json:"contentChanges"`
+}
+
+// initializeParams holds the parts of protocol.InitializeParams which were
+// added in LSP 3.17 and are missing in the protocol package.
+type initializeParams struct {
+	Capabilities struct {
+		General struct {
+			PositionEncodings []positionEncoding `json:"positionEncodings"`
+		} `json:"general"`
+	} `json:"capabilities"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
+}
+
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	PositionEncoding positionEncoding `json:"positionEncoding"`
+}
+
+// Handler returns the jsonrpc2 handler of the server. It decodes the
+// messages the protocol package can't represent correctly itself and
+// passes everything else to protocol.ServerHandler.
+func (s *Server) Handler() jsonrpc2.Handler {
+	next := protocol.ServerHandler(s, jsonrpc2.MethodNotFoundHandler)
+
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		switch req.Method() {
+		case protocol.MethodInitialize:
+			params := initializeParams{}
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ParseError, err.Error()))
+			}
+			encoding := negotiateEncoding(params.Capabilities.General.PositionEncodings)
+			s.documents.setEncoding(encoding)
+
+			return next(ctx, func(ctx context.Context, result interface{}, err error) error {
+				if initialize, ok := result.(*protocol.InitializeResult); ok && err == nil {
+					return reply(ctx, initializeResult{
+						Capabilities: serverCapabilities{
+							ServerCapabilities: initialize.Capabilities,
+							PositionEncoding:   encoding,
+						},
+						ServerInfo: initialize.ServerInfo,
+					}, nil)
+				}
+				return reply(ctx, result, err)
+			}, req)
+
+		case protocol.MethodTextDocumentDidChange:
+			params := didChangeParams{}
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.ParseError, err.Error()))
+			}
+			return reply(ctx, nil, s.didChange(ctx, params))
+		}
+		return next(ctx, reply, req)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/protocol"
+)
+
+// maxHoverReplacements limits the replacements shown in a hover, LanguageTool
+// ranks them so the first ones are the most likely.
+const maxHoverReplacements = 10
+
+// hoverContent explains a match of LanguageTool in Markdown.
+func hoverContent(match languagetool.Match, diagnostic protocol.Diagnostic) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "**%s**\n", escapeMarkdown(match.Message))
+
+	details := []string{}
+	if match.Rule.Description != "" {
+		details = append(details, escapeMarkdown(match.Rule.Description))
+	}
+	if match.Rule.Category.Name != "" {
+		details = append(details, "_"+escapeMarkdown(match.Rule.Category.Name)+"_")
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", strings.Join(details, " · "))
+	}
+
+	if context := highlightContext(match.Context); context != "" {
+		fmt.Fprintf(&b, "\n> %s\n", context)
+	}
+
+	if len(match.Replacements) > 0 {
+		b.WriteString("\nReplacements:\n")
+		for i, replacement := range match.Replacements {
+			if i == maxHoverReplacements {
+				fmt.Fprintf(&b, "\n_and %d more_\n", len(match.Replacements)-i)
+				break
+			}
+			fmt.Fprintf(&b, "%d. `%s`", i+1, strings.ReplaceAll(replacement.Value, "`", "'"))
+			if replacement.ShortDescription != "" {
+				fmt.Fprintf(&b, " %s", escapeMarkdown(replacement.ShortDescription))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if diagnostic.CodeDescription != nil {
+		fmt.Fprintf(&b, "\n[%s](%s)\n", escapeMarkdown(match.Rule.ID), diagnostic.CodeDescription.Href)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// highlightContext returns the context of a match with the error in bold.
+// The offsets of the context are UTF-16 code units like all offsets of
+// LanguageTool.
+func highlightContext(context languagetool.MatchContext) string {
+	if context.Text == "" {
+		return ""
+	}
+
+	mapper := newPositionMapper(context.Text, encodingUTF16)
+	start := mapper.byteOffset(context.Offset)
+	end := mapper.byteOffset(context.Offset + context.Length)
+	if start >= end {
+		return escapeMarkdown(context.Text)
+	}
+	return escapeMarkdown(context.Text[:start]) +
+		"**" + escapeMarkdown(context.Text[start:end]) + "**" +
+		escapeMarkdown(context.Text[end:])
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]",
+	"<", "\\<", ">", "\\>", "#", "\\#", "|", "\\|", "\n", " ",
+)
+
+// escapeMarkdown escapes text of LanguageTool, so it is shown as is.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"go.lsp.dev/protocol"
+)
+
+// positionEncoding is the unit the characters of a protocol.Position are
+// counted in, negotiated with the client on initialize.
+type positionEncoding string
+
+const (
+	encodingUTF8  positionEncoding = "utf-8"
+	encodingUTF16 positionEncoding = "utf-16"
+	encodingUTF32 positionEncoding = "utf-32"
+)
+
+// negotiateEncoding picks the first encoding the client supports, the
+// protocol defaults to utf-16.
+func negotiateEncoding(supported []positionEncoding) positionEncoding {
+	for _, encoding := range supported {
+		switch encoding {
+		case encodingUTF8, encodingUTF16, encodingUTF32:
+			return encoding
+		}
+	}
+	return encodingUTF16
+}
+
+// positionMapper converts between the offsets LanguageTool returns (UTF-16
+// code units, as it's written in Java), byte offsets into the Go string and
+// LSP positions of a text. Lines end with "\n", "\r\n" or "\r".
+type positionMapper struct {
+	text     string
+	encoding positionEncoding
+	lines    []lineStart
+}
+
+type lineStart struct {
+	offset   int
+	offset16 int
+}
+
+func newPositionMapper(text string, encoding positionEncoding) *positionMapper {
+	lines := []lineStart{{}}
+	offset16 := 0
+	for offset := 0; offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+		offset16 += runeLen(r, size, encodingUTF16)
+		if r == '\n' || (r == '\r' && (offset == len(text) || text[offset] != '\n')) {
+			lines = append(lines, lineStart{offset: offset, offset16: offset16})
+		}
+	}
+	return &positionMapper{text: text, encoding: encoding, lines: lines}
+}
+
+// byteOffset returns the byte offset of a LanguageTool offset. Offsets
+// within a rune are moved to its start.
+func (m *positionMapper) byteOffset(offset16 int) int {
+	line := sort.Search(len(m.lines), func(i int) bool { return m.lines[i].offset16 > offset16 }) - 1
+	if line < 0 {
+		return 0
+	}
+	offset, current := m.lines[line].offset, m.lines[line].offset16
+	for offset < len(m.text) {
+		r, size := utf8.DecodeRuneInString(m.text[offset:])
+		if current+runeLen(r, size, encodingUTF16) > offset16 {
+			break
+		}
+		current += runeLen(r, size, encodingUTF16)
+		offset += size
+	}
+	return offset
+}
+
+// utf16Offset returns the LanguageTool offset of a byte offset.
+func (m *positionMapper) utf16Offset(offset int) int {
+	line := m.line(offset)
+	return m.lines[line].offset16 + stringLen(m.text[m.lines[line].offset:offset], encodingUTF16)
+}
+
+// position returns the position of a byte offset.
+func (m *positionMapper) position(offset int) protocol.Position {
+	if offset > len(m.text) {
+		offset = len(m.text)
+	}
+	for offset > 0 && offset < len(m.text) && !utf8.RuneStart(m.text[offset]) {
+		offset--
+	}
+	line := m.line(offset)
+	return protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(stringLen(m.text[m.lines[line].offset:offset], m.encoding)),
+	}
+}
+
+// offset returns the byte offset of a position. Positions after the end of
+// a line or the text are moved to the end of it.
+func (m *positionMapper) offset(position protocol.Position) int {
+	if int(position.Line) >= len(m.lines) {
+		return len(m.text)
+	}
+	offset, end := m.lines[position.Line].offset, m.lineEnd(int(position.Line))
+	for character := 0; offset < end; {
+		r, size := utf8.DecodeRuneInString(m.text[offset:])
+		character += runeLen(r, size, m.encoding)
+		if character > int(position.Character) {
+			break
+		}
+		offset += size
+	}
+	return offset
+}
+
+// rangeOf returns the range of a LanguageTool match.
+func (m *positionMapper) rangeOf(offset16 int, length16 int) protocol.Range {
+	return protocol.Range{
+		Start: m.position(m.byteOffset(offset16)),
+		End:   m.position(m.byteOffset(offset16 + length16)),
+	}
+}
+
+// before reports whether position a is before b.
+func before(a protocol.Position, b protocol.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// overlaps reports whether two ranges share at least one position, ranges
+// touching each other overlap as well, so a cursor at the end of a
+// diagnostic still belongs to it.
+func overlaps(a protocol.Range, b protocol.Range) bool {
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+func (m *positionMapper) line(offset int) int {
+	return sort.Search(len(m.lines), func(i int) bool { return m.lines[i].offset > offset }) - 1
+}
+
+// lineEnd returns the byte offset of the end of a line without the line break.
+func (m *positionMapper) lineEnd(line int) int {
+	if line+1 >= len(m.lines) {
+		return len(m.text)
+	}
+	end := m.lines[line+1].offset - 1
+	if end > m.lines[line].offset && m.text[end] == '\n' && m.text[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// runeLen returns the length of a rune of size bytes in the encoding.
+func runeLen(r rune, size int, encoding positionEncoding) int {
+	switch encoding {
+	case encodingUTF8:
+		return size
+	case encodingUTF32:
+		return 1
+	default:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	}
+}
+
+func stringLen(s string, encoding positionEncoding) int {
+	if encoding == encodingUTF8 {
+		return len(s)
+	}
+	n := 0
+	for _, r := range s {
+		n += runeLen(r, 0, encoding)
+	}
+	return n
+}
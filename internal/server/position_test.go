@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+func TestPositionMapper(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		encoding positionEncoding
+		// offset16 and length16 of a LanguageTool match
+		offset16 int
+		length16 int
+		expect   protocol.Range
+	}{
+		{
+			name:     "ascii",
+			text:     "Das ist ein Tset.",
+			encoding: encodingUTF16,
+			offset16: 12, length16: 4,
+			expect: protocol.Range{Start: protocol.Position{Character: 12}, End: protocol.Position{Character: 16}},
+		},
+		{
+			name:     "umlauts in utf-16",
+			text:     "Über Änderungen wird gesprochn.",
+			encoding: encodingUTF16,
+			offset16: 21, length16: 9,
+			expect: protocol.Range{Start: protocol.Position{Character: 21}, End: protocol.Position{Character: 30}},
+		},
+		{
+			name:     "umlauts in utf-8",
+			text:     "Über Änderungen wird gesprochn.",
+			encoding: encodingUTF8,
+			offset16: 21, length16: 9,
+			expect: protocol.Range{Start: protocol.Position{Character: 23}, End: protocol.Position{Character: 32}},
+		},
+		{
+			name:     "emoji in utf-16",
+			text:     "😀 Fehlr",
+			encoding: encodingUTF16,
+			offset16: 3, length16: 5,
+			expect: protocol.Range{Start: protocol.Position{Character: 3}, End: protocol.Position{Character: 8}},
+		},
+		{
+			name:     "emoji in utf-32",
+			text:     "😀 Fehlr",
+			encoding: encodingUTF32,
+			offset16: 3, length16: 5,
+			expect: protocol.Range{Start: protocol.Position{Character: 2}, End: protocol.Position{Character: 7}},
+		},
+		{
+			name:     "cjk on the second line",
+			text:     "日本語\n日本语の文章",
+			encoding: encodingUTF16,
+			offset16: 6, length16: 2,
+			expect: protocol.Range{Start: protocol.Position{Line: 1, Character: 2}, End: protocol.Position{Line: 1, Character: 4}},
+		},
+		{
+			name:     "crlf",
+			text:     "erste Zeile\r\nzweite Zeiel",
+			encoding: encodingUTF16,
+			offset16: 20, length16: 5,
+			expect: protocol.Range{Start: protocol.Position{Line: 1, Character: 7}, End: protocol.Position{Line: 1, Character: 12}},
+		},
+		{
+			name:     "cr only",
+			text:     "eins\rzwei\rdrei",
+			encoding: encodingUTF16,
+			offset16: 10, length16: 4,
+			expect: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 2, Character: 4}},
+		},
+		{
+			name:     "match at the end of the text",
+			text:     "Text\n",
+			encoding: encodingUTF16,
+			offset16: 5, length16: 0,
+			expect: protocol.Range{Start: protocol.Position{Line: 1}, End: protocol.Position{Line: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		got := newPositionMapper(test.text, test.encoding).rangeOf(test.offset16, test.length16)
+		if got != test.expect {
+			t.Fatalf("%s: wrong range want: %+v, got: %+v", test.name, test.expect, got)
+		}
+	}
+}
+
+func TestPositionMapperOffset(t *testing.T) {
+	tests := []struct {
+		text     string
+		encoding positionEncoding
+		position protocol.Position
+		expect   int
+	}{
+		{text: "äöü", encoding: encodingUTF16, position: protocol.Position{Character: 2}, expect: 4},
+		{text: "äöü", encoding: encodingUTF8, position: protocol.Position{Character: 4}, expect: 4},
+		{text: "😀x", encoding: encodingUTF16, position: protocol.Position{Character: 2}, expect: 4},
+		// a position within a surrogate pair is moved to its start
+		{text: "😀x", encoding: encodingUTF16, position: protocol.Position{Character: 1}, expect: 0},
+		{text: "😀x", encoding: encodingUTF32, position: protocol.Position{Character: 1}, expect: 4},
+		{text: "a\r\nb", encoding: encodingUTF16, position: protocol.Position{Character: 5}, expect: 1},
+		{text: "a\r\nb", encoding: encodingUTF16, position: protocol.Position{Line: 1, Character: 1}, expect: 4},
+		{text: "a\r\nb", encoding: encodingUTF16, position: protocol.Position{Line: 7}, expect: 4},
+	}
+
+	for _, test := range tests {
+		got := newPositionMapper(test.text, test.encoding).offset(test.position)
+		if got != test.expect {
+			t.Fatalf("wrong offset of %+v in %q (%s) want: %d, got: %d", test.position, test.text, test.encoding, test.expect, got)
+		}
+	}
+}
+
+func FuzzPositionMapper(f *testing.F) {
+	for _, seed := range []string{"", "Das ist ein Test.", "Über\r\nÄnderung\rß", "😀 日本語\n\n", "\r\r\n\n"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if !utf8.ValidString(text) {
+			t.Skip()
+		}
+
+		for _, encoding := range []positionEncoding{encodingUTF8, encodingUTF16, encodingUTF32} {
+			mapper := newPositionMapper(text, encoding)
+			for offset := range text {
+				// there is no position between \r and \n
+				if offset > 0 && text[offset-1] == '\r' && text[offset] == '\n' {
+					continue
+				}
+				position := mapper.position(offset)
+				if got := mapper.offset(position); got != offset {
+					t.Fatalf("offset %d of %q (%s) is mapped to %+v and back to %d", offset, text, encoding, position, got)
+				}
+				offset16 := mapper.utf16Offset(offset)
+				if got := mapper.byteOffset(offset16); got != offset {
+					t.Fatalf("offset %d of %q is mapped to utf-16 %d and back to %d", offset, text, offset16, got)
+				}
+			}
+		}
+	})
+}
+
+func TestHandlerNegotiatesEncoding(t *testing.T) {
+	tests := []struct {
+		params string
+		expect positionEncoding
+	}{
+		{params: `{"capabilities": {}}`, expect: encodingUTF16},
+		{params: `{"capabilities": {"general": {"positionEncodings": ["utf-8", "utf-16"]}}}`, expect: encodingUTF8},
+		{params: `{"capabilities": {"general": {"positionEncodings": ["utf-7", "utf-32"]}}}`, expect: encodingUTF32},
+	}
+
+	for _, test := range tests {
+		server, _ := NewServer(zap.NewNop(), &MockServer{}, Config{})
+		req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodInitialize, json.RawMessage(test.params))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result []byte
+		server.Handler()(context.Background(), func(ctx context.Context, r interface{}, err error) error {
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err = json.Marshal(r)
+			return err
+		}, req)
+
+		capabilities := struct {
+			Capabilities struct {
+				PositionEncoding   positionEncoding `json:"positionEncoding"`
+				CodeActionProvider json.RawMessage  `json:"codeActionProvider"`
+			} `json:"capabilities"`
+		}{}
+		if err := json.Unmarshal(result, &capabilities); err != nil {
+			t.Fatal(err)
+		}
+		if capabilities.Capabilities.PositionEncoding != test.expect || capabilities.Capabilities.CodeActionProvider == nil {
+			t.Fatalf("wrong capabilities want: %s, got: %s", test.expect, result)
+		}
+		if server.documents.encoding != test.expect {
+			t.Fatalf("wrong encoding of the documents want: %s, got: %s", test.expect, server.documents.encoding)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+const (
+	// ignoreCommand hides a single match, see ignoredMatch.
+	ignoreCommand = "languagetool.ignore"
+	// disableRuleCommand disables a rule, see disableRuleArguments.
+	disableRuleCommand = "languagetool.disableRule"
+)
+
+// The scopes a rule can be disabled in.
+const (
+	scopeDocument  = "document"
+	scopeWorkspace = "workspace"
+	scopeGlobal    = "global"
+)
+
+// DefaultDisabledRulesFile returns the file of the globally disabled rules
+// in the XDG config directory.
+func DefaultDisabledRulesFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lt-lsp", "disabled-rules.txt"), nil
+}
+
+// ignoredMatch identifies a match the user chose to ignore. Offsets change
+// with every edit, so the match is recognized by its rule, text and
+// sentence.
+type ignoredMatch struct {
+	URI      uri.URI `json:"uri,omitempty"`
+	Rule     string  `json:"rule"`
+	Text     string  `json:"text"`
+	Sentence string  `json:"sentence"`
+}
+
+func (i ignoredMatch) matches(doc document, mapper *positionMapper, match languagetool.Match) bool {
+	return i.Rule == match.Rule.ID && i.Sentence == match.Sentence && i.Text == matchText(doc, mapper, match)
+}
+
+type disableRuleArguments struct {
+	Rule  string `json:"rule"`
+	Scope string `json:"scope"`
+	// URI is the document or the workspace folder the rule is disabled in.
+	URI uri.URI `json:"uri,omitempty"`
+}
+
+// ruleActions returns the actions to ignore a diagnostic or to disable its
+// rule.
+func ruleActions(diagnostic protocol.Diagnostic, data Data, document uri.URI, folder *workspaceFolder) []protocol.CodeAction {
+	ignore := ignoredMatch{URI: document, Rule: data.Rule, Text: data.Text, Sentence: data.Sentence}
+	actions := []protocol.CodeAction{
+		commandAction(diagnostic, "Ignore this occurrence", ignoreCommand, ignore),
+		commandAction(diagnostic, fmt.Sprintf("Disable rule %s in this document", data.Rule), disableRuleCommand,
+			disableRuleArguments{Rule: data.Rule, Scope: scopeDocument, URI: document}),
+	}
+	if folder != nil {
+		actions = append(actions, commandAction(diagnostic, fmt.Sprintf("Disable rule %s in workspace", data.Rule), disableRuleCommand,
+			disableRuleArguments{Rule: data.Rule, Scope: scopeWorkspace, URI: folder.URI}))
+	}
+	return append(actions, commandAction(diagnostic, fmt.Sprintf("Disable rule %s globally", data.Rule), disableRuleCommand,
+		disableRuleArguments{Rule: data.Rule, Scope: scopeGlobal}))
+}
+
+func commandAction(diagnostic protocol.Diagnostic, title string, command string, arguments ...interface{}) protocol.CodeAction {
+	return protocol.CodeAction{
+		Title:       title,
+		Kind:        protocol.QuickFix,
+		Diagnostics: []protocol.Diagnostic{diagnostic},
+		Command: &protocol.Command{
+			Title:     title,
+			Command:   command,
+			Arguments: arguments,
+		},
+	}
+}
+
+// disabledRules returns the rules disabled globally, in the workspace folder
+// of the document and in the document itself.
+func (s Server) disabledRules(doc document) []string {
+	file, key := s.documentRules(doc.URI)
+	rules := append(s.globalRules.list(), file.get(key).DisabledRules...)
+	if folder, ok := s.workspaces.folder(doc.URI); ok {
+		rules = append(rules, folder.disabledRules.list()...)
+	}
+	return rules
+}
+
+func (s Server) ignored(doc document, mapper *positionMapper, match languagetool.Match) bool {
+	file, key := s.documentRules(doc.URI)
+	for _, ignored := range file.get(key).Ignored {
+		if ignored.matches(doc, mapper, match) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Server) ignore(match ignoredMatch) error {
+	if match.Rule == "" {
+		return fmt.Errorf("%s expects a rule", ignoreCommand)
+	}
+	if _, ok := s.documents.get(match.URI); !ok {
+		return fmt.Errorf("unknown document %s", match.URI)
+	}
+	file, key := s.documentRules(match.URI)
+	if err := file.ignore(key, match); err != nil {
+		return err
+	}
+	s.scheduler.schedule(match.URI, 0)
+	return nil
+}
+
+// disableRule disables a rule and checks the affected documents again.
+func (s Server) disableRule(arguments disableRuleArguments) error {
+	if arguments.Rule == "" {
+		return fmt.Errorf("%s expects a rule", disableRuleCommand)
+	}
+
+	switch arguments.Scope {
+	case scopeDocument:
+		if _, ok := s.documents.get(arguments.URI); !ok {
+			return fmt.Errorf("unknown document %s", arguments.URI)
+		}
+		file, key := s.documentRules(arguments.URI)
+		if err := file.disableRule(key, arguments.Rule); err != nil {
+			return err
+		}
+		s.scheduler.schedule(arguments.URI, 0)
+		return nil
+	case scopeWorkspace:
+		folder, ok := s.workspaces.folder(arguments.URI)
+		if !ok || folder.URI != arguments.URI {
+			return fmt.Errorf("unknown workspace folder %s", arguments.URI)
+		}
+		if err := folder.disabledRules.add(arguments.Rule); err != nil {
+			return err
+		}
+	case scopeGlobal:
+		if err := s.globalRules.add(arguments.Rule); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown scope %q, expected %s, %s or %s", arguments.Scope, scopeDocument, scopeWorkspace, scopeGlobal)
+	}
+	s.recheck()
+	return nil
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.lsp.dev/uri"
+)
+
+// scheduler debounces the checks of documents. Scheduling a check cancels
+// the pending or running check of the same document, so only the newest
+// version is checked.
+type scheduler struct {
+	run func(ctx context.Context, uri uri.URI)
+
+	mu     sync.Mutex
+	checks map[uri.URI]*scheduledCheck
+}
+
+type scheduledCheck struct {
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func newScheduler(run func(ctx context.Context, uri uri.URI)) *scheduler {
+	return &scheduler{
+		run:    run,
+		checks: map[uri.URI]*scheduledCheck{},
+	}
+}
+
+// schedule checks the document after delay.
+func (s *scheduler) schedule(uri uri.URI, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopLocked(uri)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	check := &scheduledCheck{cancel: cancel}
+	check.timer = time.AfterFunc(delay, func() {
+		defer cancel()
+		s.run(ctx, uri)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.checks[uri] == check {
+			delete(s.checks, uri)
+		}
+	})
+	s.checks[uri] = check
+}
+
+// stop cancels the pending or running check of the document.
+func (s *scheduler) stop(uri uri.URI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopLocked(uri)
+}
+
+func (s *scheduler) stopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for uri := range s.checks {
+		s.stopLocked(uri)
+	}
+}
+
+func (s *scheduler) stopLocked(uri uri.URI) {
+	if check, ok := s.checks[uri]; ok {
+		check.timer.Stop()
+		check.cancel()
+		delete(s.checks, uri)
+	}
+}
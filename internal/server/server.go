@@ -3,8 +3,10 @@ package server
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 
+	"github.com/pascal-sochacki/languagetool-lsp/internal/markup"
 	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
 	"go.lsp.dev/protocol"
 	"go.lsp.dev/uri"
@@ -15,55 +17,73 @@ type Server struct {
 	log          *zap.Logger
 	languagetool languagetool.LanguagetoolApi
 	client       protocol.Client
+	config       *configuration
+	errors       *errorReporter
+	documents    *documentStore
+	scheduler    *scheduler
+	dictionary   *wordList
+	workspaces   *workspaceStore
+	// globalRules are the ids of the rules disabled globally.
+	globalRules *wordList
+	// globalDocuments are the choices for documents outside of workspace
+	// folders, sessionRules those for documents without a path.
+	globalDocuments *documentRulesFile
+	sessionRules    *documentRulesFile
 }
 
 // CodeAction implements protocol.Server.
 func (s Server) CodeAction(ctx context.Context, params *protocol.CodeActionParams) (result []protocol.CodeAction, err error) {
 	s.log.Info(fmt.Sprintf("%+v", params))
 
-	for _, v := range params.Context.Diagnostics {
+	diagnostics := params.Context.Diagnostics
+	if !kindRequested(params.Context.Only, protocol.QuickFix) {
+		// e.g. only source actions are requested on save
+		diagnostics = nil
+	}
 
-		if v.Range.Start.Line != params.Range.Start.Line {
-			continue
-		}
+	for _, v := range diagnostics {
 
-		if params.Range.Start.Character < v.Range.Start.Character {
+		if !overlaps(v.Range, params.Range) {
 			continue
 		}
 
-		if params.Range.End.Character > v.Range.End.Character {
+		data, ok := decodeData(v.Data)
+		if !ok {
 			continue
 		}
 
-		entries, ok := v.Data.(map[string]interface{})
-
-		for _, replacements := range entries {
-
-			replacements, _ := replacements.([]interface{})
-
-			for _, replacement := range replacements {
-				string := replacement.(string)
-
-				result = append(result, protocol.CodeAction{
-					Title: "replace with " + string,
-					Edit: &protocol.WorkspaceEdit{
-						Changes: map[uri.URI][]protocol.TextEdit{
-							params.TextDocument.URI: {
-								{
-									Range:   v.Range,
-									NewText: string,
-								},
+		for _, replacement := range data.Replacements {
+			result = append(result, protocol.CodeAction{
+				Title: "replace with " + replacement,
+				Edit: &protocol.WorkspaceEdit{
+					Changes: map[uri.URI][]protocol.TextEdit{
+						params.TextDocument.URI: {
+							{
+								Range:   v.Range,
+								NewText: replacement,
 							},
 						},
 					},
-				})
-			}
+				},
+			})
 		}
 
-		if !ok {
-			continue
+		folder, _ := s.workspaces.folder(params.TextDocument.URI)
+		if data.Word != "" {
+			result = append(result, commandAction(v, fmt.Sprintf("Add '%s' to user dictionary", data.Word), addWordCommand,
+				addWordArguments{Word: data.Word}))
+			if folder != nil {
+				result = append(result, commandAction(v, fmt.Sprintf("Add '%s' to workspace dictionary", data.Word), addWordCommand,
+					addWordArguments{Word: data.Word, Folder: folder.URI}))
+			}
 		}
+		if data.Rule != "" {
+			result = append(result, ruleActions(v, data, params.TextDocument.URI, folder)...)
+		}
+	}
 
+	if doc, ok := s.documents.get(params.TextDocument.URI); ok {
+		result = append(result, fixAllActions(doc, params)...)
 	}
 	return result, nil
 }
@@ -108,99 +128,167 @@ func (Server) Definition(ctx context.Context, params *protocol.DefinitionParams)
 	return nil, nil
 }
 
-type Data struct {
-	Replacement []string `json:"replacement"`
-}
-
 // DidChange implements protocol.Server.
 func (s *Server) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) (err error) {
-	text := params.ContentChanges[0].Text
+	return s.didChange(ctx, didChangeParams{
+		TextDocument:   params.TextDocument,
+		ContentChanges: fromContentChangeEvents(params.ContentChanges),
+	})
+}
 
-	result, err := s.languagetool.CheckText(ctx, text)
+func (s *Server) didChange(ctx context.Context, params didChangeParams) error {
+	_, err := s.documents.change(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges)
 	if err != nil {
 		s.log.Error(err.Error())
 		return err
 	}
+	s.scheduler.schedule(params.TextDocument.URI, s.config.get().Debounce)
+	return nil
+}
 
-	fmt.Printf("%+v", result)
-	s.log.Debug(fmt.Sprintf("%+v", result))
-	diagnostics := []protocol.Diagnostic{}
+// checkDocument checks the current version of a document.
+func (s *Server) checkDocument(ctx context.Context, uri uri.URI) {
+	doc, ok := s.documents.get(uri)
+	if !ok {
+		return
+	}
+	s.check(ctx, doc)
+}
 
-	lines := strings.Split(text, "\n")
+// check checks the text of the document and publishes the diagnostics.
+func (s *Server) check(ctx context.Context, doc document) {
+	config := s.config.get()
+	options := config.Check.Merge(languagetool.CheckOptions{
+		Language:      doc.Language,
+		DisabledRules: s.disabledRules(doc),
+	})
+	var result languagetool.CheckResult
+	var err error
+	if text, ok := markup.Extract(string(doc.LanguageID), doc.Text, config.Markup); ok {
+		result, err = s.languagetool.CheckAnnotatedText(ctx, text, options)
+	} else {
+		result, err = s.languagetool.CheckText(ctx, doc.Text, options)
+	}
+	if ctx.Err() != nil {
+		s.log.Debug(fmt.Sprintf("check of %s was cancelled", doc.URI))
+		return
+	}
+	if err != nil {
+		s.log.Error(err.Error())
+		s.errors.report(ctx, s.log, s.client, err)
+		return
+	}
+	s.errors.reset()
 
-	for _, v := range result.Matches {
-		start, end := findPositionAndLine(lines, v.Offset, v.Length)
+	s.log.Debug(fmt.Sprintf("%+v", result))
+	diagnostics := []protocol.Diagnostic{}
 
-		replacements := []string{}
-		for _, v := range v.Replacements {
-			replacements = append(replacements, v.Value)
+	mapper := s.documents.mapper(doc)
 
+	matches := []languagetool.Match{}
+	for _, match := range result.Matches {
+		if isMisspelling(match) && s.knownWord(doc.URI, matchText(doc, mapper, match)) {
+			continue
 		}
+		if s.ignored(doc, mapper, match) {
+			continue
+		}
+		matches = append(matches, match)
+		diagnostics = append(diagnostics, newDiagnostic(doc, mapper, match, result.Language, config))
+	}
+	// diagnostics of the server itself follow those of the matches
+	diagnostics = append(diagnostics, subjectDiagnostics(doc, mapper, config)...)
 
-		diagnostic := protocol.Diagnostic{
-			Message: fmt.Sprintf("%s (%s)", v.Message, strings.Join(replacements, ", ")),
-			Data:    Data{Replacement: replacements},
-			Range: protocol.Range{
-				Start: start,
-				End:   end,
-			}}
-
-		diagnostics = append(diagnostics, diagnostic)
+	if !s.documents.setDiagnostics(doc.URI, doc.Version, diagnostics, matches) {
+		s.log.Debug(fmt.Sprintf("dropping diagnostics of %s, it was changed or closed", doc.URI))
+		return
 	}
 	s.log.Debug(fmt.Sprintf("%+v", diagnostics))
 	s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
-		URI:         params.TextDocument.URI,
+		URI:         doc.URI,
+		Version:     uint32(doc.Version),
 		Diagnostics: diagnostics,
 	})
-	return nil
-}
-
-func findPositionAndLine(lines []string, offset int, length int) (start protocol.Position, end protocol.Position) {
-	var line uint32
-	line = 0
-
-	for {
-
-		if offset-len(lines[line]) < 0 {
-			break
-		}
-
-		offset = offset - len(lines[line])
-
-		if int(line) == len(lines)-1 {
-			break
-		}
-		line += 1
-		offset -= 1
-	}
-	start.Line = line
-	start.Character = uint32(offset)
-	end.Line = line
-	end.Character = uint32(offset) + uint32(length)
-	return start, end
 }
 
 // DidChangeConfiguration implements protocol.Server.
 func (s Server) DidChangeConfiguration(ctx context.Context, params *protocol.DidChangeConfigurationParams) (err error) {
 	s.log.Debug(fmt.Sprintf("%+v", params))
+
+	config, err := parseSettings(params.Settings)
+	if err != nil {
+		s.log.Error(err.Error())
+		return err
+	}
+	s.config.setWorkspace(config)
+	// the open documents are checked with the new settings right away
+	s.recheck()
 	return nil
 }
 
 // DidChangeWatchedFiles implements protocol.Server.
 func (s Server) DidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) (err error) {
 	s.log.Debug(fmt.Sprintf("%+v", params))
+
+	changed := false
+	for _, change := range params.Changes {
+		ok, err := s.reloadUserFile(change.URI)
+		if !ok {
+			ok, err = s.workspaces.reload(change.URI)
+		}
+		if err != nil {
+			s.log.Error(err.Error())
+		}
+		changed = changed || ok
+	}
+	if changed {
+		s.recheck()
+	}
 	return nil
 }
 
+// userFiles are the files of the user outside of the workspace folders.
+func (s Server) userFiles() []workspaceFile {
+	return []workspaceFile{s.dictionary, s.globalRules, s.globalDocuments}
+}
+
+// reloadUserFile loads a changed file of the user again. It reports whether
+// the file is one of the userFiles.
+func (s Server) reloadUserFile(file uri.URI) (bool, error) {
+	path, ok := filename(file)
+	if !ok {
+		return false, nil
+	}
+	for _, f := range s.userFiles() {
+		if f.file() != "" && f.file() == path {
+			return true, f.load()
+		}
+	}
+	return false, nil
+}
+
 // DidChangeWorkspaceFolders implements protocol.Server.
 func (s Server) DidChangeWorkspaceFolders(ctx context.Context, params *protocol.DidChangeWorkspaceFoldersParams) (err error) {
 	s.log.Debug(fmt.Sprintf("%+v", params))
+
+	s.workspaces.remove(params.Event.Removed...)
+	for _, err := range s.workspaces.add(params.Event.Added...) {
+		s.log.Error(err.Error())
+	}
+	s.recheck()
 	return nil
 }
 
 // DidClose implements protocol.Server.
-func (Server) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) (err error) {
-	return nil
+func (s Server) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) (err error) {
+	s.scheduler.stop(params.TextDocument.URI)
+	s.documents.close(params.TextDocument.URI)
+
+	// clear the diagnostics, the editor keeps them otherwise
+	return s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+		URI:         params.TextDocument.URI,
+		Diagnostics: []protocol.Diagnostic{},
+	})
 }
 
 // DidCreateFiles implements protocol.Server.
@@ -215,8 +303,10 @@ func (Server) DidDeleteFiles(ctx context.Context, params *protocol.DeleteFilesPa
 
 // DidOpen implements protocol.Server.
 func (s Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) (err error) {
-	s.log.Debug(params.TextDocument.Text)
+	s.log.Debug(fmt.Sprintf("opened %s", params.TextDocument.URI))
 
+	s.documents.open(params.TextDocument)
+	s.scheduler.schedule(params.TextDocument.URI, 0)
 	return nil
 }
 
@@ -259,9 +349,81 @@ func (Server) DocumentSymbol(ctx context.Context, params *protocol.DocumentSymbo
 // ExecuteCommand implements protocol.Server.
 func (s Server) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (result interface{}, err error) {
 	s.log.Debug(fmt.Sprintf("%+v", params))
+
+	err = fmt.Errorf("unknown command %s", params.Command)
+	for _, command := range commands {
+		if command.name == params.Command {
+			err = command.run(ctx, s, params.Arguments)
+			break
+		}
+	}
+	// the error is shown to the user, editors differ in reporting a failed
+	// request
+	if err != nil {
+		s.log.Error(err.Error())
+		s.showMessage(ctx, protocol.MessageTypeError, fmt.Sprintf("LanguageTool: %s failed: %s", params.Command, err))
+	}
 	return nil, nil
 }
 
+// addWord adds a word to the user dictionary and checks all documents
+// again, so it disappears everywhere.
+func (s Server) addWord(ctx context.Context, word string) error {
+	if err := s.dictionary.add(word); err != nil {
+		return err
+	}
+
+	if words := s.config.get().Words; words != nil {
+		if err := words.AddWord(ctx, word); err != nil {
+			s.log.Error(err.Error())
+			s.showMessage(ctx, protocol.MessageTypeWarning, fmt.Sprintf("LanguageTool: could not add '%s' to the account dictionary: %s", word, err))
+		}
+	}
+
+	s.recheck()
+	return nil
+}
+
+// addWorkspaceWord adds a word to the dictionary of a workspace folder,
+// which is shared with everyone working on the repository.
+func (s Server) addWorkspaceWord(word string, folder uri.URI) error {
+	f, ok := s.workspaces.folder(folder)
+	if !ok || f.URI != folder {
+		return fmt.Errorf("unknown workspace folder %s", folder)
+	}
+	if err := f.dictionary.add(word); err != nil {
+		return err
+	}
+	s.recheck()
+	return nil
+}
+
+// knownWord reports whether a word is in the user dictionary or in the
+// dictionary of the workspace folder of the document.
+func (s Server) knownWord(document uri.URI, word string) bool {
+	if s.dictionary.contains(word) {
+		return true
+	}
+	folder, ok := s.workspaces.folder(document)
+	return ok && folder.dictionary.contains(word)
+}
+
+// recheck checks all open documents again.
+func (s Server) recheck() {
+	for _, doc := range s.documents.all() {
+		s.scheduler.schedule(doc.URI, 0)
+	}
+}
+
+func (s Server) showMessage(ctx context.Context, messageType protocol.MessageType, message string) {
+	if err := s.client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type:    messageType,
+		Message: message,
+	}); err != nil {
+		s.log.Error(err.Error())
+	}
+}
+
 // Exit implements protocol.Server.
 func (Server) Exit(ctx context.Context) (err error) {
 	return nil
@@ -278,8 +440,37 @@ func (Server) Formatting(ctx context.Context, params *protocol.DocumentFormattin
 }
 
 // Hover implements protocol.Server.
-func (Server) Hover(ctx context.Context, params *protocol.HoverParams) (result *protocol.Hover, err error) {
-	return nil, nil
+func (s Server) Hover(ctx context.Context, params *protocol.HoverParams) (result *protocol.Hover, err error) {
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	// diagnostics of an older version point into the old text
+	diagnostics, matches := doc.currentDiagnostics()
+	position := protocol.Range{Start: params.Position, End: params.Position}
+	contents := []string{}
+	var hoverRange *protocol.Range
+	for i, diagnostic := range diagnostics {
+		if !overlaps(diagnostic.Range, position) || i >= len(matches) {
+			continue
+		}
+		contents = append(contents, hoverContent(matches[i], diagnostic))
+		if hoverRange == nil {
+			hoverRange = &diagnostics[i].Range
+		}
+	}
+	if len(contents) == 0 {
+		return nil, nil
+	}
+
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: strings.Join(contents, "\n\n---\n\n"),
+		},
+		Range: hoverRange,
+	}, nil
 }
 
 // Implementation implements protocol.Server.
@@ -296,20 +487,77 @@ func (Server) IncomingCalls(ctx context.Context, params *protocol.CallHierarchyI
 func (s Server) Initialize(ctx context.Context, params *protocol.InitializeParams) (result *protocol.InitializeResult, err error) {
 	s.log.Debug("called Initialize")
 
+	config, err := parseSettings(params.InitializationOptions)
+	if err != nil {
+		s.log.Error(err.Error())
+	}
+	s.config.setWorkspace(config)
+
+	folders := params.WorkspaceFolders
+	if len(folders) == 0 && params.RootURI != "" {
+		folders = []protocol.WorkspaceFolder{{URI: string(params.RootURI)}}
+	}
+	for _, err := range s.workspaces.add(folders...) {
+		s.log.Error(err.Error())
+	}
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil {
+		s.workspaces.setWatch(workspace.DidChangeWatchedFiles.DynamicRegistration)
+	}
+
 	result = &protocol.InitializeResult{}
 
 	result.ServerInfo = &protocol.ServerInfo{}
 	result.ServerInfo.Name = "languagetool-lsp"
 	result.ServerInfo.Version = "0.0.1"
 	result.Capabilities = protocol.ServerCapabilities{}
-	result.Capabilities.TextDocumentSync = 1
-	result.Capabilities.CodeActionProvider = true
+	result.Capabilities.TextDocumentSync = protocol.TextDocumentSyncOptions{
+		OpenClose: true,
+		Change:    protocol.TextDocumentSyncKindIncremental,
+	}
+	result.Capabilities.CodeActionProvider = protocol.CodeActionOptions{
+		CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix, sourceFixAll},
+	}
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.ExecuteCommandProvider = &protocol.ExecuteCommandOptions{
+		Commands: commandNames(),
+	}
+	result.Capabilities.Workspace = &protocol.ServerCapabilitiesWorkspace{
+		WorkspaceFolders: &protocol.ServerCapabilitiesWorkspaceFolders{
+			Supported:           true,
+			ChangeNotifications: true,
+		},
+	}
 	return result, nil
 }
 
 // Initialized implements protocol.Server.
 func (s Server) Initialized(ctx context.Context, params *protocol.InitializedParams) (err error) {
 	s.log.Debug("called Initialized")
+
+	if !s.workspaces.canWatch() {
+		return nil
+	}
+	// the editor reports changes of the shared files of the workspace folders
+	// and of the files of the user, e.g. a dictionary synced from another
+	// machine
+	watchers := []protocol.FileSystemWatcher{{GlobPattern: "**/" + workspaceDir + "/*.{txt,json}"}}
+	for _, f := range s.userFiles() {
+		if f.file() != "" {
+			watchers = append(watchers, protocol.FileSystemWatcher{GlobPattern: filepath.ToSlash(f.file())})
+		}
+	}
+	err = s.client.RegisterCapability(ctx, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{
+			{
+				ID:              "languagetool-workspace-files",
+				Method:          protocol.MethodWorkspaceDidChangeWatchedFiles,
+				RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+			},
+		},
+	})
+	if err != nil {
+		s.log.Error(err.Error())
+	}
 	return nil
 }
 
@@ -400,7 +648,8 @@ func (Server) ShowDocument(ctx context.Context, params *protocol.ShowDocumentPar
 }
 
 // Shutdown implements protocol.Server.
-func (Server) Shutdown(ctx context.Context) (err error) {
+func (s Server) Shutdown(ctx context.Context) (err error) {
+	s.scheduler.stopAll()
 	return nil
 }
 
@@ -449,11 +698,30 @@ func (Server) WorkDoneProgressCancel(ctx context.Context, params *protocol.WorkD
 	return nil
 }
 
-func NewServer(log *zap.Logger, languagetool languagetool.LanguagetoolApi) (*Server, func(protocol.Client)) {
+func NewServer(log *zap.Logger, languagetool languagetool.LanguagetoolApi, config Config) (*Server, func(protocol.Client)) {
 	a := &Server{
 		log:          log,
 		languagetool: languagetool,
+		config:       newConfiguration(config),
+		errors:       &errorReporter{},
+		documents:    newDocumentStore(),
+		dictionary:   newWordList(config.Dictionary),
+		workspaces:   newWorkspaceStore(),
+
+		globalRules:     newWordList(config.DisabledRulesFile),
+		globalDocuments: newDocumentRulesFile(config.DocumentRulesFile),
+		sessionRules:    newDocumentRulesFile(""),
+	}
+	if err := a.dictionary.load(); err != nil {
+		log.Error(err.Error())
+	}
+	if err := a.globalRules.load(); err != nil {
+		log.Error(err.Error())
+	}
+	if err := a.globalDocuments.load(); err != nil {
+		log.Error(err.Error())
 	}
+	a.scheduler = newScheduler(a.checkDocument)
 	b := func(client protocol.Client) {
 		a.client = client
 
@@ -2,10 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pascal-sochacki/languagetool-lsp/internal/markup"
 	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
 	"go.lsp.dev/protocol"
 	"go.lsp.dev/uri"
@@ -13,84 +19,180 @@ import (
 )
 
 type MockServer struct {
-	result *languagetool.CheckResult
+	mu      sync.Mutex
+	result  *languagetool.CheckResult
+	err     error
+	options languagetool.CheckOptions
+	calls   int
+	// annotated is the last annotated text, nil if plain text was checked
+	annotated *languagetool.AnnotatedText
+	// block delays the answer until it is closed
+	block chan struct{}
 }
 
-func (m *MockServer) CheckText(ctx context.Context, text string) (languagetool.CheckResult, error) {
-	return *m.result, nil
+func (m *MockServer) CheckAnnotatedText(ctx context.Context, text languagetool.AnnotatedText, options languagetool.CheckOptions) (languagetool.CheckResult, error) {
+	m.mu.Lock()
+	m.annotated = &text
+	m.mu.Unlock()
+	return m.check(ctx, options)
+}
+
+func (m *MockServer) CheckText(ctx context.Context, text string, options languagetool.CheckOptions) (languagetool.CheckResult, error) {
+	m.mu.Lock()
+	m.annotated = nil
+	m.mu.Unlock()
+	return m.check(ctx, options)
+}
+
+func (m *MockServer) check(ctx context.Context, options languagetool.CheckOptions) (languagetool.CheckResult, error) {
+	m.mu.Lock()
+	m.options = options
+	m.calls++
+	block := m.block
+	result, err := m.result, m.err
+	m.mu.Unlock()
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return languagetool.CheckResult{}, ctx.Err()
+		}
+	}
+	if err != nil {
+		return languagetool.CheckResult{}, err
+	}
+	return *result, nil
 }
 
 func (m *MockServer) setCheckResult(result languagetool.CheckResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.result = &result
 }
 
+func (m *MockServer) getCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+func (m *MockServer) getAnnotated() *languagetool.AnnotatedText {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.annotated
+}
+
+func (m *MockServer) getOptions() languagetool.CheckOptions {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.options
+}
+
 type ClientRecorder struct {
-	Diagostics []protocol.PublishDiagnosticsParams
+	mu            sync.Mutex
+	Diagostics    []protocol.PublishDiagnosticsParams
+	Messages      []protocol.ShowMessageParams
+	Registrations []protocol.Registration
 }
 
 // ApplyEdit implements protocol.Client.
-func (ClientRecorder) ApplyEdit(ctx context.Context, params *protocol.ApplyWorkspaceEditParams) (result bool, err error) {
+func (*ClientRecorder) ApplyEdit(ctx context.Context, params *protocol.ApplyWorkspaceEditParams) (result bool, err error) {
 	panic("unimplemented")
 }
 
 // Configuration implements protocol.Client.
-func (ClientRecorder) Configuration(ctx context.Context, params *protocol.ConfigurationParams) (result []interface{}, err error) {
+func (*ClientRecorder) Configuration(ctx context.Context, params *protocol.ConfigurationParams) (result []interface{}, err error) {
 	panic("unimplemented")
 }
 
 // LogMessage implements protocol.Client.
-func (ClientRecorder) LogMessage(ctx context.Context, params *protocol.LogMessageParams) (err error) {
+func (*ClientRecorder) LogMessage(ctx context.Context, params *protocol.LogMessageParams) (err error) {
 	panic("unimplemented")
 }
 
 // Progress implements protocol.Client.
-func (ClientRecorder) Progress(ctx context.Context, params *protocol.ProgressParams) (err error) {
+func (*ClientRecorder) Progress(ctx context.Context, params *protocol.ProgressParams) (err error) {
 	panic("unimplemented")
 }
 
 // PublishDiagnostics implements protocol.Client.
 func (c *ClientRecorder) PublishDiagnostics(ctx context.Context, params *protocol.PublishDiagnosticsParams) (err error) {
-	fmt.Printf("%+v", *params)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.Diagostics = append(c.Diagostics, *params)
 	return nil
 }
 
-func (c ClientRecorder) getDiagostics() []protocol.PublishDiagnosticsParams {
-	return c.Diagostics
+func (c *ClientRecorder) getDiagostics() []protocol.PublishDiagnosticsParams {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]protocol.PublishDiagnosticsParams{}, c.Diagostics...)
+}
+
+func (c *ClientRecorder) getMessages() []protocol.ShowMessageParams {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]protocol.ShowMessageParams{}, c.Messages...)
+}
+
+// waitForDiagostics waits until diagnostics were published count times,
+// the documents are checked in the background.
+func (c *ClientRecorder) waitForDiagostics(t *testing.T, count int) []protocol.PublishDiagnosticsParams {
+	t.Helper()
+	waitFor(t, func() bool { return len(c.getDiagostics()) >= count })
+	return c.getDiagostics()
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the server")
+		}
+		time.Sleep(time.Millisecond)
+	}
 }
 
 // RegisterCapability implements protocol.Client.
-func (ClientRecorder) RegisterCapability(ctx context.Context, params *protocol.RegistrationParams) (err error) {
-	panic("unimplemented")
+func (c *ClientRecorder) RegisterCapability(ctx context.Context, params *protocol.RegistrationParams) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Registrations = append(c.Registrations, params.Registrations...)
+	return nil
 }
 
 // ShowMessage implements protocol.Client.
-func (ClientRecorder) ShowMessage(ctx context.Context, params *protocol.ShowMessageParams) (err error) {
-	panic("unimplemented")
+func (c *ClientRecorder) ShowMessage(ctx context.Context, params *protocol.ShowMessageParams) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages, *params)
+	return nil
 }
 
 // ShowMessageRequest implements protocol.Client.
-func (ClientRecorder) ShowMessageRequest(ctx context.Context, params *protocol.ShowMessageRequestParams) (result *protocol.MessageActionItem, err error) {
+func (*ClientRecorder) ShowMessageRequest(ctx context.Context, params *protocol.ShowMessageRequestParams) (result *protocol.MessageActionItem, err error) {
 	panic("unimplemented")
 }
 
 // Telemetry implements protocol.Client.
-func (ClientRecorder) Telemetry(ctx context.Context, params interface{}) (err error) {
+func (*ClientRecorder) Telemetry(ctx context.Context, params interface{}) (err error) {
 	panic("unimplemented")
 }
 
 // UnregisterCapability implements protocol.Client.
-func (ClientRecorder) UnregisterCapability(ctx context.Context, params *protocol.UnregistrationParams) (err error) {
+func (*ClientRecorder) UnregisterCapability(ctx context.Context, params *protocol.UnregistrationParams) (err error) {
 	panic("unimplemented")
 }
 
 // WorkDoneProgressCreate implements protocol.Client.
-func (ClientRecorder) WorkDoneProgressCreate(ctx context.Context, params *protocol.WorkDoneProgressCreateParams) (err error) {
+func (*ClientRecorder) WorkDoneProgressCreate(ctx context.Context, params *protocol.WorkDoneProgressCreateParams) (err error) {
 	panic("unimplemented")
 }
 
 // WorkspaceFolders implements protocol.Client.
-func (ClientRecorder) WorkspaceFolders(ctx context.Context) (result []protocol.WorkspaceFolder, err error) {
+func (*ClientRecorder) WorkspaceFolders(ctx context.Context) (result []protocol.WorkspaceFolder, err error) {
 	panic("unimplemented")
 }
 
@@ -150,7 +252,7 @@ func TestDidChange(t *testing.T) {
 		mock := &MockServer{}
 
 		recorder := &ClientRecorder{}
-		server, init := NewServer(zap.NewNop(), mock)
+		server, init := NewServer(zap.NewNop(), mock, Config{})
 		init(recorder)
 		params := protocol.DidChangeTextDocumentParams{}
 		params.ContentChanges = []protocol.TextDocumentContentChangeEvent{
@@ -159,10 +261,10 @@ func TestDidChange(t *testing.T) {
 				Text: test.text,
 			},
 		}
-		fmt.Printf("%+v", test.answer)
 
 		mock.setCheckResult(test.answer)
 		server.DidChange(context.Background(), &params)
+		recorder.waitForDiagostics(t, 1)
 
 		if len(recorder.getDiagostics()) != len(test.expect) {
 			t.Fatalf("wrong length of diagostics want: %d, got: %d", len(test.expect), len(recorder.getDiagostics()))
@@ -204,11 +306,40 @@ func TestCodeAction(t *testing.T) {
 		},
 	}
 
+	multiLine := protocol.Range{Start: protocol.Position{Line: 0, Character: 20}, End: protocol.Position{Line: 1, Character: 4}}
+	tests = append(tests,
+		TestCodeActionTest{
+			Diagnostic: []protocol.Diagnostic{
+				{
+					Range: multiLine,
+					Data:  map[string]interface{}{"replacements": []interface{}{"newString"}},
+				},
+			},
+			TextDocument: protocol.TextDocumentIdentifier{URI: "test"},
+			Range:        protocol.Range{Start: protocol.Position{Line: 1, Character: 2}, End: protocol.Position{Line: 1, Character: 2}},
+			Result: []protocol.CodeAction{{Title: "replace with newString", Edit: &protocol.WorkspaceEdit{
+				Changes: map[uri.URI][]protocol.TextEdit{
+					"test": {{NewText: "newString", Range: multiLine}},
+				},
+			}}},
+		},
+		TestCodeActionTest{
+			Diagnostic: []protocol.Diagnostic{
+				{
+					Range: multiLine,
+					Data:  map[string]interface{}{"replacements": []interface{}{"newString"}},
+				},
+			},
+			TextDocument: protocol.TextDocumentIdentifier{URI: "test"},
+			Range:        protocol.Range{Start: protocol.Position{Line: 1, Character: 5}, End: protocol.Position{Line: 1, Character: 5}},
+		},
+	)
+
 	for _, test := range tests {
 		mock := &MockServer{}
 
 		recorder := &ClientRecorder{}
-		server, init := NewServer(zap.NewNop(), mock)
+		server, init := NewServer(zap.NewNop(), mock, Config{})
 		init(recorder)
 
 		actions, _ := server.CodeAction(context.Background(), &protocol.CodeActionParams{
@@ -229,3 +360,1057 @@ func TestCodeAction(t *testing.T) {
 
 	}
 }
+
+func TestDidChangeConfiguration(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{
+		Check: languagetool.CheckOptions{Language: "en-US", DisabledRules: []string{"WHITESPACE_RULE"}},
+	})
+	init(recorder)
+
+	err := server.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{
+			"languagetool": map[string]interface{}{
+				"check": map[string]interface{}{
+					"language":      "de-DE",
+					"level":         "picky",
+					"disabledRules": []interface{}{"EN_QUOTES"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := protocol.DidChangeTextDocumentParams{}
+	params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: "Das ist ein Test."}}
+	server.DidChange(context.Background(), &params)
+	recorder.waitForDiagostics(t, 1)
+
+	expect := languagetool.CheckOptions{
+		Language:      "de-DE",
+		Level:         "picky",
+		DisabledRules: []string{"WHITESPACE_RULE", "EN_QUOTES"},
+	}
+	if !reflect.DeepEqual(mock.getOptions(), expect) {
+		t.Fatalf("wrong check options want: %+v, got: %+v", expect, mock.getOptions())
+	}
+}
+
+func TestDidChangeConfigurationRechecks(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	enabledOnly := true
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{
+		Check: languagetool.CheckOptions{EnabledOnly: &enabledOnly, EnabledRules: []string{"EN_A_VS_AN"}},
+	})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.txt", Version: 1, Text: "Das ist ein Test."},
+	})
+	recorder.waitForDiagostics(t, 1)
+
+	err := server.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"check": map[string]interface{}{"enabledOnly": false}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.waitForDiagostics(t, 2)
+
+	if options := mock.getOptions(); options.EnabledOnly == nil || *options.EnabledOnly {
+		t.Fatalf("expected the workspace to turn off enabledOnly, got: %+v", options)
+	}
+}
+
+func TestDidChangeShowsErrors(t *testing.T) {
+	mock := &MockServer{err: fmt.Errorf("check: %w", languagetool.ErrUnauthorized)}
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	params := protocol.DidChangeTextDocumentParams{}
+	params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: "Apffelstaft"}}
+
+	for i := 0; i < 2; i++ {
+		if err := server.DidChange(context.Background(), &params); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, func() bool { return mock.getCalls() > i })
+	}
+
+	messages := recorder.getMessages()
+	if len(messages) != 1 {
+		t.Fatalf("wrong number of messages want: 1, got: %d", len(messages))
+	}
+	if messages[0].Type != protocol.MessageTypeError {
+		t.Fatalf("wrong message type want: %s, got: %s", protocol.MessageTypeError, messages[0].Type)
+	}
+	if len(recorder.getDiagostics()) != 0 {
+		t.Fatalf("expected no diagnostics, got: %+v", recorder.getDiagostics())
+	}
+}
+
+func TestDidOpenAndClose(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{{Message: "Möglicher Tippfehler gefunden.", Offset: 0, Length: 11}},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", LanguageID: "markdown", Version: 1, Text: "Apffelstaft"},
+	})
+
+	if diagnostics := recorder.waitForDiagostics(t, 1); len(diagnostics[0].Diagnostics) != 1 {
+		t.Fatalf("expected diagnostics after open, got: %+v", diagnostics)
+	}
+
+	doc, ok := server.documents.get("file:///test.md")
+	if !ok || doc.Text != "Apffelstaft" || doc.LanguageID != "markdown" || len(doc.Diagnostics) != 1 {
+		t.Fatalf("document was not stored: %+v", doc)
+	}
+
+	server.DidClose(context.Background(), &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.md"},
+	})
+
+	if len(recorder.getDiagostics()) != 2 || len(recorder.getDiagostics()[1].Diagnostics) != 0 {
+		t.Fatalf("expected diagnostics to be cleared after close, got: %+v", recorder.getDiagostics())
+	}
+	if _, ok := server.documents.get("file:///test.md"); ok {
+		t.Fatal("document was not removed")
+	}
+}
+
+func TestDidChangeDebounces(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{Debounce: 20 * time.Millisecond})
+	init(recorder)
+
+	for version := int32(1); version <= 5; version++ {
+		params := protocol.DidChangeTextDocumentParams{}
+		params.TextDocument.URI = "file:///test.md"
+		params.TextDocument.Version = version
+		params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: fmt.Sprintf("Version %d", version)}}
+		server.DidChange(context.Background(), &params)
+	}
+
+	diagnostics := recorder.waitForDiagostics(t, 1)
+	time.Sleep(50 * time.Millisecond)
+
+	if mock.getCalls() != 1 || len(recorder.getDiagostics()) != 1 {
+		t.Fatalf("expected one check, got: %d checks, %d publishes", mock.getCalls(), len(recorder.getDiagostics()))
+	}
+	if diagnostics[0].Version != 5 {
+		t.Fatalf("wrong version of the diagnostics want: 5, got: %d", diagnostics[0].Version)
+	}
+}
+
+func TestDidChangeCancelsStaleChecks(t *testing.T) {
+	mock := &MockServer{block: make(chan struct{})}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", Version: 1, Text: "alt"},
+	})
+	waitFor(t, func() bool { return mock.getCalls() == 1 })
+
+	// a new version arrives while the first one is checked
+	params := protocol.DidChangeTextDocumentParams{}
+	params.TextDocument.URI = "file:///test.md"
+	params.TextDocument.Version = 2
+	params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: "neu"}}
+	server.DidChange(context.Background(), &params)
+	waitFor(t, func() bool { return mock.getCalls() == 2 })
+	close(mock.block)
+
+	diagnostics := recorder.waitForDiagostics(t, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	if len(recorder.getDiagostics()) != 1 || diagnostics[0].Version != 2 {
+		t.Fatalf("expected only the diagnostics of version 2, got: %+v", recorder.getDiagostics())
+	}
+}
+
+func TestDidChangeMultiLineRanges(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{
+			// "wird\ngesprochen" was soft-wrapped
+			{Message: "Zusammenschreibung", Offset: 16, Length: 15},
+		},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	params := protocol.DidChangeTextDocumentParams{}
+	params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: "Über Änderungen wird\ngesprochen."}}
+	server.DidChange(context.Background(), &params)
+
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+	expect := protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 16},
+		End:   protocol.Position{Line: 1, Character: 10},
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Range != expect {
+		t.Fatalf("wrong range want: %+v, got: %+v", expect, diagnostics)
+	}
+}
+
+func TestDidChangeSeverity(t *testing.T) {
+	match := func(ruleID string, categoryID string, issueType string) languagetool.Match {
+		return languagetool.Match{Rule: languagetool.Rule{ID: ruleID, IssueType: issueType, Category: languagetool.Category{ID: categoryID}}}
+	}
+
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{
+			match("GERMAN_SPELLER_RULE", "TYPOS", "misspelling"),
+			match("DE_AGREEMENT", "GRAMMAR", "grammar"),
+			match("PASSIVE_VOICE", "STYLE", "style"),
+			match("WHITESPACE_RULE", "TYPOGRAPHY", "whitespace"),
+			match("DE_CASE", "CASING", "misspelling"),
+			match("UNKNOWN", "MISC", "something-new"),
+		},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{
+		Severity: SeverityConfig{
+			Categories: map[string]string{"casing": "warning", "typography": "error"},
+			Rules:      map[string]string{"whitespace_rule": "hint"},
+		},
+	})
+	init(recorder)
+
+	params := protocol.DidChangeTextDocumentParams{}
+	params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: "Text"}}
+	server.DidChange(context.Background(), &params)
+
+	expect := []protocol.DiagnosticSeverity{
+		protocol.DiagnosticSeverityError,
+		protocol.DiagnosticSeverityWarning,
+		protocol.DiagnosticSeverityInformation,
+		protocol.DiagnosticSeverityHint,
+		protocol.DiagnosticSeverityWarning,
+		protocol.DiagnosticSeverityInformation,
+	}
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Severity != expect[i] {
+			t.Fatalf("wrong severity of %s want: %s, got: %s", diagnostic.Code, expect[i], diagnostic.Severity)
+		}
+		if diagnostic.Source != "languagetool" || diagnostic.Code == nil {
+			t.Fatalf("missing source or code: %+v", diagnostic)
+		}
+	}
+}
+
+func TestSeverityConfigMerge(t *testing.T) {
+	// viper reads the keys of the user config in lower case
+	user := SeverityConfig{Rules: map[string]string{"whitespace_rule": "hint", "de_case": "hint"}}
+	workspace := SeverityConfig{Rules: map[string]string{"WHITESPACE_RULE": "error"}}
+	config := Config{}.merge(Config{Severity: user}).merge(Config{Severity: workspace}).Severity
+
+	tests := map[string]protocol.DiagnosticSeverity{
+		"WHITESPACE_RULE": protocol.DiagnosticSeverityError,
+		"DE_CASE":         protocol.DiagnosticSeverityHint,
+	}
+	for rule, expect := range tests {
+		got := config.severity(languagetool.Match{Rule: languagetool.Rule{ID: rule}})
+		if got != expect {
+			t.Errorf("wrong severity of %s want: %s, got: %s", rule, expect, got)
+		}
+	}
+}
+
+func TestDidChangeRuleDocumentation(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Language: languagetool.Language{Code: "de-DE"},
+		Matches: []languagetool.Match{
+			{
+				Offset: 27, Length: 4, Sentence: "Das ist ein Satz mit einem Fehlr.",
+				Rule: languagetool.Rule{ID: "GERMAN_SPELLER_RULE"},
+			},
+			{
+				Offset: 0, Length: 5, Sentence: "Erste Zeile.",
+				Rule: languagetool.Rule{ID: "DE_CASE", URLs: []languagetool.URL{{Value: "https://example.com/case"}}},
+			},
+		},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	params := protocol.DidChangeTextDocumentParams{}
+	params.TextDocument.URI = "file:///test.md"
+	params.ContentChanges = []protocol.TextDocumentContentChangeEvent{{Text: "Erste Zeile.\nDas ist ein Satz mit einem Fehlr."}}
+	server.DidChange(context.Background(), &params)
+
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got: %+v", diagnostics)
+	}
+
+	tests := []struct {
+		href     string
+		sentence protocol.Range
+	}{
+		{
+			href: "https://community.languagetool.org/rule/show/GERMAN_SPELLER_RULE?lang=de-DE",
+			sentence: protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 0},
+				End:   protocol.Position{Line: 1, Character: 33},
+			},
+		},
+		{
+			href: "https://example.com/case",
+			sentence: protocol.Range{
+				Start: protocol.Position{Line: 0, Character: 0},
+				End:   protocol.Position{Line: 0, Character: 12},
+			},
+		},
+	}
+	for i, test := range tests {
+		diagnostic := diagnostics[i]
+		if diagnostic.CodeDescription == nil || string(diagnostic.CodeDescription.Href) != test.href {
+			t.Errorf("wrong href want: %s, got: %+v", test.href, diagnostic.CodeDescription)
+		}
+		if len(diagnostic.RelatedInformation) != 1 {
+			t.Fatalf("expected related information, got: %+v", diagnostic.RelatedInformation)
+		}
+		related := diagnostic.RelatedInformation[0]
+		if related.Location.URI != "file:///test.md" || related.Location.Range != test.sentence {
+			t.Errorf("wrong sentence location want: %+v, got: %+v", test.sentence, related.Location)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Language: languagetool.Language{Code: "de-DE"},
+		Matches: []languagetool.Match{
+			{
+				Message: "Möglicher Tippfehler gefunden.", Offset: 14, Length: 5,
+				Context:      languagetool.MatchContext{Text: "Über Änderung Fehlr reden.", Offset: 14, Length: 5},
+				Replacements: []languagetool.Replacement{{Value: "Fehler"}, {Value: "Fehl"}},
+				Rule: languagetool.Rule{
+					ID: "GERMAN_SPELLER_RULE", Description: "Möglicher Rechtschreibfehler",
+					Category: languagetool.Category{ID: "TYPOS", Name: "Mögliche Tippfehler"},
+				},
+			},
+		},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", Version: 1, Text: "Über Änderung Fehlr reden."},
+	})
+	recorder.waitForDiagostics(t, 1)
+
+	hover := func(character uint32) *protocol.Hover {
+		result, err := server.Hover(context.Background(), &protocol.HoverParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.md"},
+				Position:     protocol.Position{Line: 0, Character: character},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := hover(3); result != nil {
+		t.Fatalf("expected no hover outside of diagnostics, got: %+v", result)
+	}
+
+	result := hover(15)
+	if result == nil {
+		t.Fatal("expected a hover on the diagnostic")
+	}
+	expect := `**Möglicher Tippfehler gefunden.**
+
+Möglicher Rechtschreibfehler · _Mögliche Tippfehler_
+
+> Über Änderung **Fehlr** reden.
+
+Replacements:
+1. ` + "`Fehler`" + `
+2. ` + "`Fehl`" + `
+
+[GERMAN\_SPELLER\_RULE](https://community.languagetool.org/rule/show/GERMAN_SPELLER_RULE?lang=de-DE)`
+	if result.Contents.Kind != protocol.Markdown || result.Contents.Value != expect {
+		t.Fatalf("wrong hover want:\n%s\ngot:\n%s", expect, result.Contents.Value)
+	}
+	wantRange := protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 14},
+		End:   protocol.Position{Line: 0, Character: 19},
+	}
+	if result.Range == nil || *result.Range != wantRange {
+		t.Fatalf("wrong range want: %+v, got: %+v", wantRange, result.Range)
+	}
+
+	// after an edit the diagnostics do not fit the text until the recheck
+	_, err := server.documents.change("file:///test.md", 2, []contentChange{{Range: &protocol.Range{}, Text: "Wir wollen "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := hover(15); result != nil {
+		t.Fatalf("expected no hover from outdated diagnostics, got: %+v", result)
+	}
+}
+
+type wordsFunc func(ctx context.Context, word string) error
+
+func (f wordsFunc) AddWord(ctx context.Context, word string) error {
+	return f(ctx, word)
+}
+
+func TestAddWord(t *testing.T) {
+	misspelling := languagetool.Rule{ID: "GERMAN_SPELLER_RULE", IssueType: "misspelling", Category: languagetool.Category{ID: "TYPOS"}}
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{
+			{Offset: 4, Length: 6, Rule: misspelling, Replacements: []languagetool.Replacement{{Value: "Golfer"}}},
+			{Offset: 11, Length: 5, Rule: languagetool.Rule{ID: "DE_CASE", IssueType: "misspelling", Category: languagetool.Category{ID: "CASING"}}},
+		},
+	})
+
+	file := filepath.Join(t.TempDir(), "lt-lsp", "dictionary.txt")
+	accountWords := make(chan string, 1)
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{
+		Dictionary: file,
+		Words: wordsFunc(func(ctx context.Context, word string) error {
+			accountWords <- word
+			return nil
+		}),
+	})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", Version: 1, Text: "Ein Gopher läuft."},
+	})
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got: %+v", diagnostics)
+	}
+
+	// the editor sends the data back as plain JSON
+	raw, _ := json.Marshal(diagnostics)
+	var sent []protocol.Diagnostic
+	json.Unmarshal(raw, &sent)
+
+	actions, err := server.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.md"},
+		Range:        protocol.Range{Start: protocol.Position{Character: 5}, End: protocol.Position{Character: 5}},
+		Context:      protocol.CodeActionContext{Diagnostics: sent},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) < 2 || actions[1].Command == nil {
+		t.Fatalf("expected a replacement and an add to dictionary action, got: %+v", actions)
+	}
+	command := actions[1].Command
+	if actions[1].Title != "Add 'Gopher' to user dictionary" || command.Command != "languagetool.addWord" {
+		t.Fatalf("wrong action: %+v", actions[1])
+	}
+
+	if _, err := server.ExecuteCommand(context.Background(), &protocol.ExecuteCommandParams{
+		Command:   command.Command,
+		Arguments: command.Arguments,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil || string(content) != "Gopher\n" {
+		t.Fatalf("word was not persisted: %q, %v", content, err)
+	}
+	if word := <-accountWords; word != "Gopher" {
+		t.Fatalf("wrong word added to the account want: Gopher, got: %s", word)
+	}
+
+	diagnostics = recorder.waitForDiagostics(t, 2)[1].Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Code != "DE_CASE" {
+		t.Fatalf("expected only the casing diagnostic, got: %+v", diagnostics)
+	}
+
+	// a new server loads the dictionary from the file
+	reloaded := newWordList(file)
+	if err := reloaded.load(); err != nil || !reloaded.contains("Gopher") {
+		t.Fatalf("dictionary was not loaded: %v", err)
+	}
+
+	// the dictionary was changed on another machine and synced
+	if err := os.WriteFile(file, []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server.DidChangeWatchedFiles(context.Background(), &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{Type: protocol.FileChangeTypeChanged, URI: uri.File(file)}},
+	})
+	if diagnostics := recorder.waitForDiagostics(t, 3)[2].Diagnostics; len(diagnostics) != 2 {
+		t.Fatalf("expected the user dictionary to be reloaded, got: %+v", diagnostics)
+	}
+}
+
+func TestWorkspaceDictionary(t *testing.T) {
+	misspelling := languagetool.Rule{ID: "GERMAN_SPELLER_RULE", IssueType: "misspelling", Category: languagetool.Category{ID: "TYPOS"}}
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{
+			{Offset: 4, Length: 6, Rule: misspelling},
+			// the type marks unknown words of rules without a category
+			{Offset: 11, Length: 6, Type: languagetool.MatchType{TypeName: "UnknownWord"}, Rule: languagetool.Rule{ID: "GERMAN_SPELLER_RULE"}},
+		},
+	})
+
+	root := t.TempDir()
+	dictionary := filepath.Join(root, ".languagetool", "dictionary.txt")
+	if err := os.MkdirAll(filepath.Dir(dictionary), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dictionary, []byte("# product names\nGopher\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	folder := uri.File(root)
+	if _, err := server.Initialize(context.Background(), &protocol.InitializeParams{
+		WorkspaceFolders: []protocol.WorkspaceFolder{{URI: string(folder), Name: "test"}},
+		Capabilities: protocol.ClientCapabilities{
+			Workspace: &protocol.WorkspaceClientCapabilities{
+				DidChangeWatchedFiles: &protocol.DidChangeWatchedFilesWorkspaceClientCapabilities{DynamicRegistration: true},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	server.Initialized(context.Background(), &protocol.InitializedParams{})
+	if len(recorder.Registrations) != 1 || recorder.Registrations[0].Method != "workspace/didChangeWatchedFiles" {
+		t.Fatalf("expected a file watcher to be registered, got: %+v", recorder.Registrations)
+	}
+
+	document := uri.File(filepath.Join(root, "docs", "test.md"))
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: document, Version: 1, Text: "Ein Gopher Ferris"},
+	})
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected the workspace word to be known, got: %+v", diagnostics)
+	}
+
+	raw, _ := json.Marshal(diagnostics)
+	var sent []protocol.Diagnostic
+	json.Unmarshal(raw, &sent)
+	actions, _ := server.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: document},
+		Range:        sent[0].Range,
+		Context:      protocol.CodeActionContext{Diagnostics: sent},
+	})
+	titles := []string{}
+	for _, action := range actions {
+		titles = append(titles, action.Title)
+	}
+	expect := []string{
+		"Add 'Ferris' to user dictionary",
+		"Add 'Ferris' to workspace dictionary",
+		"Ignore this occurrence",
+		"Disable rule GERMAN_SPELLER_RULE in this document",
+		"Disable rule GERMAN_SPELLER_RULE in workspace",
+		"Disable rule GERMAN_SPELLER_RULE globally",
+	}
+	if !reflect.DeepEqual(titles, expect) {
+		t.Fatalf("wrong actions want: %v, got: %v", expect, titles)
+	}
+
+	if _, err := server.ExecuteCommand(context.Background(), &protocol.ExecuteCommandParams{
+		Command:   actions[1].Command.Command,
+		Arguments: actions[1].Command.Arguments,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(dictionary)
+	if string(content) != "# product names\nGopher\nFerris\n" {
+		t.Fatalf("word was not added to the workspace dictionary: %q", content)
+	}
+	if diagnostics := recorder.waitForDiagostics(t, 2)[1].Diagnostics; len(diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got: %+v", diagnostics)
+	}
+
+	// a teammate removed the words
+	if err := os.WriteFile(dictionary, []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server.DidChangeWatchedFiles(context.Background(), &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{Type: protocol.FileChangeTypeChanged, URI: uri.File(dictionary)}},
+	})
+	if diagnostics := recorder.waitForDiagostics(t, 3)[2].Diagnostics; len(diagnostics) != 2 {
+		t.Fatalf("expected the dictionary to be reloaded, got: %+v", diagnostics)
+	}
+}
+
+func TestIgnoreAndDisableRules(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{
+			{Offset: 0, Length: 3, Sentence: "Das das ist doppelt.", Rule: languagetool.Rule{ID: "GERMAN_WORD_REPEAT_RULE"}},
+			{Offset: 12, Length: 3, Sentence: "Das das ist doppelt.", Rule: languagetool.Rule{ID: "PASSIVE_VOICE"}},
+		},
+	})
+
+	root := t.TempDir()
+	global := filepath.Join(root, "config", "disabled-rules.txt")
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{DisabledRulesFile: global})
+	init(recorder)
+
+	folder := uri.File(filepath.Join(root, "workspace"))
+	server.Initialize(context.Background(), &protocol.InitializeParams{
+		WorkspaceFolders: []protocol.WorkspaceFolder{{URI: string(folder), Name: "test"}},
+	})
+
+	document := uri.File(filepath.Join(root, "workspace", "test.md"))
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: document, Version: 1, Text: "Das das ist doppelt."},
+	})
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+
+	raw, _ := json.Marshal(diagnostics)
+	var sent []protocol.Diagnostic
+	json.Unmarshal(raw, &sent)
+	actions, _ := server.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: document},
+		Range:        sent[0].Range,
+		Context:      protocol.CodeActionContext{Diagnostics: sent[:1]},
+	})
+	if len(actions) != 4 {
+		t.Fatalf("expected 4 actions, got: %+v", actions)
+	}
+
+	execute := func(action protocol.CodeAction) {
+		t.Helper()
+		// the editor sends the arguments back as plain JSON
+		raw, _ := json.Marshal(action.Command.Arguments)
+		var arguments []interface{}
+		json.Unmarshal(raw, &arguments)
+		if _, err := server.ExecuteCommand(context.Background(), &protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: arguments,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	execute(actions[0])
+	diagnostics = recorder.waitForDiagostics(t, 2)[1].Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Code != "PASSIVE_VOICE" {
+		t.Fatalf("expected the occurrence to be ignored, got: %+v", diagnostics)
+	}
+
+	tests := []struct {
+		action protocol.CodeAction
+		file   string
+	}{
+		{action: actions[1]},
+		{action: actions[2], file: filepath.Join(root, "workspace", ".languagetool", "disabled-rules.txt")},
+		{action: actions[3], file: global},
+	}
+	for i, test := range tests {
+		execute(test.action)
+		recorder.waitForDiagostics(t, 3+i)
+
+		if rules := mock.getOptions().DisabledRules; !reflect.DeepEqual(rules, []string{"GERMAN_WORD_REPEAT_RULE"}) {
+			t.Fatalf("%s: wrong disabled rules: %v", test.action.Title, rules)
+		}
+		if test.file == "" {
+			continue
+		}
+		content, err := os.ReadFile(test.file)
+		if err != nil || string(content) != "GERMAN_WORD_REPEAT_RULE\n" {
+			t.Fatalf("%s: rule was not persisted: %q, %v", test.action.Title, content, err)
+		}
+	}
+
+	// ignoring the occurrence again, e.g. from a stale diagnostic, keeps a
+	// single entry
+	execute(actions[0])
+
+	// the choices for the document survive a restart
+	content, err := os.ReadFile(filepath.Join(root, "workspace", ".languagetool", "documents.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	persisted := map[string]documentRules{}
+	if err := json.Unmarshal(content, &persisted); err != nil {
+		t.Fatal(err)
+	}
+	expect := documentRules{
+		DisabledRules: []string{"GERMAN_WORD_REPEAT_RULE"},
+		Ignored:       []ignoredMatch{{Rule: "GERMAN_WORD_REPEAT_RULE", Text: "Das", Sentence: "Das das ist doppelt."}},
+	}
+	if !reflect.DeepEqual(persisted["test.md"], expect) {
+		t.Fatalf("wrong rules of the document want: %+v, got: %s", expect, content)
+	}
+
+	recorder = &ClientRecorder{}
+	server, init = NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+	server.Initialize(context.Background(), &protocol.InitializeParams{
+		WorkspaceFolders: []protocol.WorkspaceFolder{{URI: string(folder), Name: "test"}},
+	})
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: document, Version: 1, Text: "Das das ist doppelt."},
+	})
+	diagnostics = recorder.waitForDiagostics(t, 1)[0].Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Code != "PASSIVE_VOICE" {
+		t.Fatalf("expected the occurrence to stay ignored, got: %+v", diagnostics)
+	}
+	if rules := mock.getOptions().DisabledRules; !reflect.DeepEqual(rules, []string{"GERMAN_WORD_REPEAT_RULE"}) {
+		t.Fatalf("expected the rule to stay disabled in the document, got: %v", rules)
+	}
+}
+
+func TestFixAll(t *testing.T) {
+	replacements := func(values ...string) []languagetool.Replacement {
+		result := []languagetool.Replacement{}
+		for _, value := range values {
+			result = append(result, languagetool.Replacement{Value: value})
+		}
+		return result
+	}
+
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{
+			{Offset: 0, Length: 3, Replacements: replacements("Das")},
+			{Offset: 4, Length: 3, Replacements: replacements("ist", "isst")},
+			{Offset: 8, Length: 4, Replacements: replacements("ein")},
+			// overlaps the previous match
+			{Offset: 8, Length: 10, Replacements: replacements("ein Text")},
+			{Offset: 13, Length: 5, Replacements: replacements("Text")},
+			{Offset: 19, Length: 2, Replacements: replacements()},
+		},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", Version: 3, Text: "das ist eine Texxt ok"},
+	})
+	recorder.waitForDiagostics(t, 1)
+
+	edit := func(start uint32, end uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Character: start}, End: protocol.Position{Character: end}},
+			NewText: text,
+		}
+	}
+	selection := protocol.Range{Start: protocol.Position{Character: 4}, End: protocol.Position{Character: 12}}
+
+	tests := []struct {
+		only   []protocol.CodeActionKind
+		rng    protocol.Range
+		kind   protocol.CodeActionKind
+		expect []protocol.TextEdit
+	}{
+		{
+			only:   []protocol.CodeActionKind{"source.fixAll"},
+			kind:   "source.fixAll.languagetool",
+			expect: []protocol.TextEdit{edit(0, 3, "Das"), edit(8, 12, "ein"), edit(13, 18, "Text")},
+		},
+		{
+			only:   []protocol.CodeActionKind{"quickfix"},
+			rng:    selection,
+			kind:   "quickfix",
+			expect: []protocol.TextEdit{edit(8, 12, "ein")},
+		},
+	}
+	for _, test := range tests {
+		actions, err := server.CodeAction(context.Background(), &protocol.CodeActionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.md"},
+			Range:        test.rng,
+			Context:      protocol.CodeActionContext{Only: test.only},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actions) != 1 || actions[0].Kind != test.kind || actions[0].Edit == nil {
+			t.Fatalf("expected one %s action, got: %+v", test.kind, actions)
+		}
+
+		changes := actions[0].Edit.DocumentChanges
+		if len(changes) != 1 || changes[0].TextDocument.Version == nil || *changes[0].TextDocument.Version != 3 {
+			t.Fatalf("expected an edit of version 3, got: %+v", changes)
+		}
+		if !reflect.DeepEqual(changes[0].Edits, test.expect) {
+			t.Fatalf("wrong edits want: %+v, got: %+v", test.expect, changes[0].Edits)
+		}
+	}
+
+	// the ranges of the diagnostics point into the old text until the recheck
+	_, err := server.documents.change("file:///test.md", 4, []contentChange{{Range: &protocol.Range{}, Text: "XYZ "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions, err := server.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.md"},
+		Context:      protocol.CodeActionContext{Only: []protocol.CodeActionKind{"source.fixAll"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Fatalf("expected no fix all action for outdated diagnostics, got: %+v", actions)
+	}
+}
+
+type CachedMockServer struct {
+	MockServer
+	cleared int
+}
+
+func (m *CachedMockServer) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cleared++
+	return nil
+}
+
+func TestExecuteCommand(t *testing.T) {
+	mock := &CachedMockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	result, _ := server.Initialize(context.Background(), &protocol.InitializeParams{})
+	if result.Capabilities.ExecuteCommandProvider == nil || len(result.Capabilities.ExecuteCommandProvider.Commands) != len(commands) {
+		t.Fatalf("expected the commands to be advertised, got: %+v", result.Capabilities.ExecuteCommandProvider)
+	}
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", Version: 1, Text: "Text"},
+	})
+	recorder.waitForDiagostics(t, 1)
+
+	execute := func(command string, argument string) error {
+		arguments := []interface{}{}
+		if argument != "" {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(argument), &decoded); err != nil {
+				t.Fatal(err)
+			}
+			arguments = append(arguments, decoded)
+		}
+		_, err := server.ExecuteCommand(context.Background(), &protocol.ExecuteCommandParams{Command: command, Arguments: arguments})
+		return err
+	}
+
+	if err := execute("languagetool.checkDocument", `{"uri": "file:///test.md"}`); err != nil {
+		t.Fatal(err)
+	}
+	recorder.waitForDiagostics(t, 2)
+
+	if err := execute("languagetool.setLanguage", `{"uri": "file:///test.md", "language": "de-CH"}`); err != nil {
+		t.Fatal(err)
+	}
+	recorder.waitForDiagostics(t, 3)
+	if language := mock.getOptions().Language; language != "de-CH" {
+		t.Fatalf("wrong language of the document want: de-CH, got: %s", language)
+	}
+
+	if err := execute("languagetool.clearCache", ""); err != nil {
+		t.Fatal(err)
+	}
+	recorder.waitForDiagostics(t, 4)
+	if mock.cleared != 1 {
+		t.Fatalf("expected the cache to be cleared once, got: %d", mock.cleared)
+	}
+
+	if err := execute("languagetool.setLanguage", `{"language": "en-GB"}`); err != nil {
+		t.Fatal(err)
+	}
+	recorder.waitForDiagostics(t, 5)
+	if language := server.config.get().Check.Language; language != "en-GB" {
+		t.Fatalf("wrong language want: en-GB, got: %s", language)
+	}
+
+	errors := []struct {
+		command  string
+		argument string
+	}{
+		{command: "languagetool.unknown"},
+		{command: "languagetool.checkDocument", argument: `{"uri": "file:///closed.md"}`},
+		{command: "languagetool.setLanguage", argument: `"de-DE"`},
+		{command: "languagetool.addWord", argument: `{"word": ""}`},
+	}
+	for i, test := range errors {
+		// the error is only shown, it is not reported a second time
+		if err := execute(test.command, test.argument); err != nil {
+			t.Fatalf("expected no error for %s %s, got: %v", test.command, test.argument, err)
+		}
+		if messages := recorder.getMessages(); len(messages) != i+1 || messages[i].Type != protocol.MessageTypeError {
+			t.Fatalf("expected the error of %s to be shown, got: %+v", test.command, messages)
+		}
+	}
+}
+
+func TestDidOpenMarkdown(t *testing.T) {
+	mock := &MockServer{}
+	// LanguageTool counts offsets in the whole text including the markup
+	mock.setCheckResult(languagetool.CheckResult{
+		Matches: []languagetool.Match{{Message: "Möglicher Tippfehler gefunden.", Offset: 22, Length: 5}},
+	})
+
+	recorder := &ClientRecorder{}
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI: "file:///test.md", LanguageID: "markdown", Version: 1,
+			Text: "# Titel\n\n```\ncode\n```\nFehlr.\n",
+		},
+	})
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+
+	annotated := mock.getAnnotated()
+	if annotated == nil || annotated.Prose() != "Titel\n\n\n\nFehlr.\n" {
+		t.Fatalf("expected the prose to be checked as annotated text, got: %+v", annotated)
+	}
+	expect := protocol.Range{
+		Start: protocol.Position{Line: 5, Character: 0},
+		End:   protocol.Position{Line: 5, Character: 5},
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Range != expect {
+		t.Fatalf("wrong range want: %+v, got: %+v", expect, diagnostics)
+	}
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.txt", LanguageID: "plaintext", Version: 1, Text: "Fehlr"},
+	})
+	recorder.waitForDiagostics(t, 2)
+	if mock.getAnnotated() != nil {
+		t.Fatal("expected plain text to be checked as text")
+	}
+}
+
+func TestDidOpenGitCommit(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	recorder := &ClientRecorder{}
+	length := 10
+	server, init := NewServer(zap.NewNop(), mock, Config{
+		GitCommit: GitCommitConfig{SubjectLength: &length},
+		Severity:  SeverityConfig{Rules: map[string]string{"subject_length": "hint"}},
+	})
+	init(recorder)
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI: "file:///COMMIT_EDITMSG", LanguageID: "gitcommit", Version: 1,
+			Text: "# comment\nÄndere den Parser\n\nSigned-off-by: Jane Doe\n",
+		},
+	})
+	diagnostics := recorder.waitForDiagostics(t, 1)[0].Diagnostics
+
+	annotated := mock.getAnnotated()
+	if annotated == nil || annotated.Prose() != "Ändere den Parser\n\n" {
+		t.Fatalf("expected the message to be checked as annotated text, got: %+v", annotated)
+	}
+	expect := protocol.Range{
+		Start: protocol.Position{Line: 1, Character: 10},
+		End:   protocol.Position{Line: 1, Character: 17},
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Range != expect || diagnostics[0].Code != subjectLengthCode {
+		t.Fatalf("wrong diagnostic of the subject want: %+v, got: %+v", expect, diagnostics)
+	}
+	if diagnostics[0].Severity != protocol.DiagnosticSeverityHint {
+		t.Fatalf("wrong severity of the subject want: %s, got: %s", protocol.DiagnosticSeverityHint, diagnostics[0].Severity)
+	}
+
+	hover, err := server.Hover(context.Background(), &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///COMMIT_EDITMSG"},
+			Position:     protocol.Position{Line: 1, Character: 12},
+		},
+	})
+	if err != nil || hover != nil {
+		t.Fatalf("expected no hover without a match, got: %+v %v", hover, err)
+	}
+
+	// 0 disables the check of the user config
+	server.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"languagetool": map[string]interface{}{
+			"gitCommit": map[string]interface{}{"subjectLength": 0},
+		}},
+	})
+	if diagnostics := recorder.waitForDiagostics(t, 2)[1].Diagnostics; len(diagnostics) != 0 {
+		t.Fatalf("expected the check of the subject to be disabled, got: %+v", diagnostics)
+	}
+}
+
+func TestDidOpenLaTeX(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	recorder := &ClientRecorder{}
+	config := Config{Markup: markup.Options{LaTeX: markup.LaTeXOptions{MarkupCommands: []string{"todo"}}}}
+	server, init := NewServer(zap.NewNop(), mock, config)
+	init(recorder)
+
+	server.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"languagetool": map[string]interface{}{
+			"markup": map[string]interface{}{"latex": map[string]interface{}{"markupCommands": []string{"fixme"}}},
+		}},
+	})
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI: "file:///paper.tex", LanguageID: "latex", Version: 1,
+			Text: "\\section{Titel}\n\\todo{a}\\fixme{b}Siehe \\cite{knuth}.\n",
+		},
+	})
+	recorder.waitForDiagostics(t, 1)
+
+	annotated := mock.getAnnotated()
+	if annotated == nil || annotated.Prose() != "Titel\nSiehe x.\n" {
+		t.Fatalf("expected the prose to be checked as annotated text, got: %+v", annotated)
+	}
+}
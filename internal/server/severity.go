@@ -0,0 +1,99 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/protocol"
+)
+
+// SeverityConfig overrides the severity of diagnostics by the id of the
+// category or rule, e.g. {"rules": {"WHITESPACE_RULE": "hint"}}. Possible
+// values are "error", "warning", "information" and "hint".
+type SeverityConfig struct {
+	Categories map[string]string `json:"categories,omitempty"`
+	Rules      map[string]string `json:"rules,omitempty"`
+}
+
+func (c SeverityConfig) merge(other SeverityConfig) SeverityConfig {
+	return SeverityConfig{
+		Categories: mergeMaps(c.Categories, other.Categories),
+		Rules:      mergeMaps(c.Rules, other.Rules),
+	}
+}
+
+// issueTypeSeverities maps the issue types of LanguageTool rules, see
+// http://www.w3.org/International/multilingualweb/lt/drafts/its20/its20.html#lqissue-typevalues
+var issueTypeSeverities = map[string]protocol.DiagnosticSeverity{
+	"misspelling":          protocol.DiagnosticSeverityError,
+	"grammar":              protocol.DiagnosticSeverityWarning,
+	"duplication":          protocol.DiagnosticSeverityWarning,
+	"inconsistency":        protocol.DiagnosticSeverityWarning,
+	"terminology":          protocol.DiagnosticSeverityWarning,
+	"mistranslation":       protocol.DiagnosticSeverityWarning,
+	"untranslated":         protocol.DiagnosticSeverityWarning,
+	"style":                protocol.DiagnosticSeverityInformation,
+	"register":             protocol.DiagnosticSeverityInformation,
+	"locale-violation":     protocol.DiagnosticSeverityInformation,
+	"typographical":        protocol.DiagnosticSeverityHint,
+	"whitespace":           protocol.DiagnosticSeverityHint,
+	"formatting":           protocol.DiagnosticSeverityHint,
+	"non-conformance":      protocol.DiagnosticSeverityHint,
+	"uncategorized":        protocol.DiagnosticSeverityInformation,
+	"other":                protocol.DiagnosticSeverityInformation,
+	"characters":           protocol.DiagnosticSeverityInformation,
+	"internationalization": protocol.DiagnosticSeverityInformation,
+}
+
+// severity returns the severity of a match. The rule overrides the category
+// which overrides the issue type.
+func (c SeverityConfig) severity(match languagetool.Match) protocol.DiagnosticSeverity {
+	if severity, ok := lookupSeverity(c.Rules, match.Rule.ID); ok {
+		return severity
+	}
+	if severity, ok := lookupSeverity(c.Categories, match.Rule.Category.ID); ok {
+		return severity
+	}
+	if severity, ok := issueTypeSeverities[match.Rule.IssueType]; ok {
+		return severity
+	}
+	return protocol.DiagnosticSeverityInformation
+}
+
+// lookupSeverity finds the id in severities merged by mergeMaps.
+func lookupSeverity(severities map[string]string, id string) (protocol.DiagnosticSeverity, bool) {
+	value, ok := severities[strings.ToUpper(id)]
+	if !ok || id == "" {
+		return 0, false
+	}
+	return parseSeverity(value)
+}
+
+func parseSeverity(value string) (protocol.DiagnosticSeverity, bool) {
+	switch strings.ToLower(value) {
+	case "error":
+		return protocol.DiagnosticSeverityError, true
+	case "warning":
+		return protocol.DiagnosticSeverityWarning, true
+	case "information", "info":
+		return protocol.DiagnosticSeverityInformation, true
+	case "hint":
+		return protocol.DiagnosticSeverityHint, true
+	default:
+		return 0, false
+	}
+}
+
+// mergeMaps combines the severities of both maps with the ids in upper case,
+// viper stores the keys of the user config in lower case but the workspace
+// config keeps them.
+func mergeMaps(a map[string]string, b map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, value := range a {
+		result[strings.ToUpper(key)] = value
+	}
+	for key, value := range b {
+		result[strings.ToUpper(key)] = value
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// contentChange is a protocol.TextDocumentContentChangeEvent. The protocol
+// package can't tell a missing range from a range at the start of the
+// document, here a nil range means the whole text is replaced.
+type contentChange struct {
+	Range *protocol.Range `json:"range,omitempty"`
+	Text  string          `json:"text"`
+}
+
+func fromContentChangeEvents(events []protocol.TextDocumentContentChangeEvent) []contentChange {
+	changes := make([]contentChange, len(events))
+	for i, event := range events {
+		changes[i] = contentChange{Text: event.Text}
+		// an empty range which deletes nothing is taken as a full change
+		if event.Range != (protocol.Range{}) || event.RangeLength != 0 {
+			r := event.Range
+			changes[i].Range = &r
+		}
+	}
+	return changes
+}
+
+// applyChanges applies the changes in order, every range refers to the text
+// after the previous change.
+func applyChanges(text string, changes []contentChange, encoding positionEncoding) (string, error) {
+	for _, change := range changes {
+		if change.Range == nil {
+			text = change.Text
+			continue
+		}
+
+		mapper := newPositionMapper(text, encoding)
+		start := mapper.offset(change.Range.Start)
+		end := mapper.offset(change.Range.End)
+		if end < start {
+			return text, fmt.Errorf("invalid range %+v", *change.Range)
+		}
+		text = text[:start] + change.Text + text[end:]
+	}
+	return text, nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pascal-sochacki/languagetool-lsp/pkg/languagetool"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+func textRange(startLine, startCharacter, endLine, endCharacter uint32) *protocol.Range {
+	return &protocol.Range{
+		Start: protocol.Position{Line: startLine, Character: startCharacter},
+		End:   protocol.Position{Line: endLine, Character: endCharacter},
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		changes []contentChange
+		expect  string
+	}{
+		{
+			name:    "full change",
+			text:    "old",
+			changes: []contentChange{{Text: "new"}},
+			expect:  "new",
+		},
+		{
+			name:    "insert at start",
+			text:    "Test",
+			changes: []contentChange{{Range: textRange(0, 0, 0, 0), Text: "Ein "}},
+			expect:  "Ein Test",
+		},
+		{
+			name: "multiple edits refer to the previous result",
+			text: "Das ist ein Tset.\nZweite Zeile",
+			changes: []contentChange{
+				{Range: textRange(0, 12, 0, 16), Text: "Test"},
+				{Range: textRange(1, 0, 1, 6), Text: "Andere"},
+				{Range: textRange(0, 17, 1, 0), Text: " "},
+			},
+			expect: "Das ist ein Test. Andere Zeile",
+		},
+		{
+			name: "crlf",
+			text: "erste\r\nzweite\r\ndritte",
+			changes: []contentChange{
+				{Range: textRange(1, 0, 1, 6), Text: "2."},
+				{Range: textRange(2, 6, 2, 6), Text: "\r\nvierte"},
+			},
+			expect: "erste\r\n2.\r\ndritte\r\nvierte",
+		},
+		{
+			name: "lines ending with cr only",
+			text: "eins\rzwei",
+			changes: []contentChange{
+				{Range: textRange(1, 0, 1, 4), Text: "drei"},
+			},
+			expect: "eins\rdrei",
+		},
+		{
+			name: "append at the end",
+			text: "Zeile\n",
+			changes: []contentChange{
+				{Range: textRange(1, 0, 1, 0), Text: "neue Zeile"},
+			},
+			expect: "Zeile\nneue Zeile",
+		},
+		{
+			name: "positions after the end are moved to the end",
+			text: "kurz\nText",
+			changes: []contentChange{
+				{Range: textRange(0, 2, 0, 100), Text: "rz"},
+				{Range: textRange(5, 0, 8, 0), Text: "!"},
+			},
+			expect: "kurz\nText!",
+		},
+		{
+			name: "characters are counted in utf-16",
+			text: "Grüße 😀 Tset",
+			changes: []contentChange{
+				{Range: textRange(0, 9, 0, 13), Text: "Test"},
+			},
+			expect: "Grüße 😀 Test",
+		},
+		{
+			name: "delete everything",
+			text: "alles\nweg\n",
+			changes: []contentChange{
+				{Range: textRange(0, 0, 2, 0), Text: ""},
+			},
+			expect: "",
+		},
+	}
+
+	for _, test := range tests {
+		got, err := applyChanges(test.text, test.changes, encodingUTF16)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if got != test.expect {
+			t.Fatalf("%s: wrong text want: %q, got: %q", test.name, test.expect, got)
+		}
+	}
+}
+
+func TestHandlerDidChange(t *testing.T) {
+	mock := &MockServer{}
+	mock.setCheckResult(languagetool.CheckResult{})
+
+	server, init := NewServer(zap.NewNop(), mock, Config{})
+	init(&ClientRecorder{})
+
+	server.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.md", Version: 1, Text: "Test"},
+	})
+
+	handler := server.Handler()
+	for i, params := range []string{
+		// insert at the start of the document
+		`{"textDocument": {"uri": "file:///test.md", "version": 2}, "contentChanges": [{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 0}}, "text": "Ein "}]}`,
+		// replace the whole document
+		`{"textDocument": {"uri": "file:///test.md", "version": 3}, "contentChanges": [{"text": "Ganz neu"}]}`,
+		`{"textDocument": {"uri": "file:///test.md", "version": 4}, "contentChanges": [{"range": {"start": {"line": 0, "character": 8}, "end": {"line": 0, "character": 8}}, "text": "!"}]}`,
+	} {
+		req, err := jsonrpc2.NewNotification(protocol.MethodTextDocumentDidChange, json.RawMessage(params))
+		if err != nil {
+			t.Fatal(err)
+		}
+		replied := false
+		handler(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+			replied = true
+			if err != nil {
+				t.Fatalf("change %d failed: %s", i, err)
+			}
+			return nil
+		}, req)
+		if !replied {
+			t.Fatalf("change %d was not handled", i)
+		}
+	}
+
+	doc, _ := server.documents.get("file:///test.md")
+	if doc.Text != "Ganz neu!" || doc.Version != 4 {
+		t.Fatalf("wrong document want: %q (4), got: %q (%d)", "Ganz neu!", doc.Text, doc.Version)
+	}
+}
+
+func TestChangeMakesDiagnosticsStale(t *testing.T) {
+	documents := newDocumentStore()
+	documents.open(protocol.TextDocumentItem{URI: "file:///test.md", Version: 1, Text: "das ist"})
+	diagnostics := []protocol.Diagnostic{{Message: "Großschreibung"}}
+	if !documents.setDiagnostics("file:///test.md", 1, diagnostics, []languagetool.Match{{Length: 3}}) {
+		t.Fatal("diagnostics of the current version were dropped")
+	}
+
+	doc, _ := documents.get("file:///test.md")
+	if got, _ := doc.currentDiagnostics(); len(got) != 1 {
+		t.Fatalf("expected the diagnostics of the current version, got: %+v", got)
+	}
+
+	doc, err := documents.change("file:///test.md", 2, []contentChange{{Range: &protocol.Range{}, Text: "XYZ "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, matches := doc.currentDiagnostics(); got != nil || matches != nil {
+		t.Fatalf("expected no diagnostics after a change, got: %+v %+v", got, matches)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// workspaceDir is the directory of the files shared with the repository.
+const workspaceDir = ".languagetool"
+
+// workspaceFolder is a folder opened in the editor and the files of it,
+// which are committed with the repository.
+type workspaceFolder struct {
+	URI        uri.URI
+	root       string
+	dictionary *wordList
+	// disabledRules are the ids of the rules disabled in the folder.
+	disabledRules *wordList
+	// documents are the choices for single documents of the folder.
+	documents *documentRulesFile
+}
+
+// workspaceFile is a file of a workspace folder, it is loaded again when it
+// changes.
+type workspaceFile interface {
+	load() error
+	file() string
+}
+
+func newWorkspaceFolder(folder uri.URI, root string) *workspaceFolder {
+	path := filepath.Join(root, workspaceDir)
+	return &workspaceFolder{
+		URI:           folder,
+		root:          root,
+		dictionary:    newWordList(filepath.Join(path, "dictionary.txt")),
+		disabledRules: newWordList(filepath.Join(path, "disabled-rules.txt")),
+		documents:     newDocumentRulesFile(filepath.Join(path, documentRulesName)),
+	}
+}
+
+func (f *workspaceFolder) files() []workspaceFile {
+	return []workspaceFile{f.dictionary, f.disabledRules, f.documents}
+}
+
+// contains reports whether a path is inside of the folder.
+func (f *workspaceFolder) contains(path string) bool {
+	return path == f.root || strings.HasPrefix(path, strings.TrimSuffix(f.root, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+// filename returns the path of a file URI. Documents of the editor may use
+// other schemes like untitled:, which have no path.
+func filename(u uri.URI) (string, bool) {
+	parsed, err := url.ParseRequestURI(string(u))
+	if err != nil || parsed.Scheme != uri.FileScheme {
+		return "", false
+	}
+	return u.Filename(), true
+}
+
+// workspaceStore keeps the workspace folders of the editor, it is safe for
+// concurrent use.
+type workspaceStore struct {
+	mu      sync.RWMutex
+	folders map[uri.URI]*workspaceFolder
+	// watch is set if the editor can watch files for the server.
+	watch bool
+}
+
+func newWorkspaceStore() *workspaceStore {
+	return &workspaceStore{folders: map[uri.URI]*workspaceFolder{}}
+}
+
+// add adds the folders and loads their files, the errors of files which
+// could not be loaded are returned.
+func (w *workspaceStore) add(folders ...protocol.WorkspaceFolder) []error {
+	errs := []error{}
+	for _, folder := range folders {
+		root, ok := filename(uri.URI(folder.URI))
+		if !ok {
+			continue
+		}
+		f := newWorkspaceFolder(uri.URI(folder.URI), root)
+		for _, file := range f.files() {
+			if err := file.load(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		w.mu.Lock()
+		w.folders[f.URI] = f
+		w.mu.Unlock()
+	}
+	return errs
+}
+
+func (w *workspaceStore) remove(folders ...protocol.WorkspaceFolder) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, folder := range folders {
+		delete(w.folders, uri.URI(folder.URI))
+	}
+}
+
+// folder returns the innermost workspace folder containing the document.
+func (w *workspaceStore) folder(document uri.URI) (*workspaceFolder, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	path, ok := filename(document)
+	if !ok {
+		return nil, false
+	}
+	var result *workspaceFolder
+	for _, folder := range w.folders {
+		if folder.contains(path) && (result == nil || len(folder.URI) > len(result.URI)) {
+			result = folder
+		}
+	}
+	return result, result != nil
+}
+
+// reload loads a changed file of a workspace folder again. It reports
+// whether the file belongs to a workspace folder.
+func (w *workspaceStore) reload(file uri.URI) (bool, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	path, ok := filename(file)
+	if !ok {
+		return false, nil
+	}
+	for _, folder := range w.folders {
+		for _, file := range folder.files() {
+			if file.file() == path {
+				return true, file.load()
+			}
+		}
+	}
+	return false, nil
+}
+
+func (w *workspaceStore) setWatch(watch bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watch = watch
+}
+
+func (w *workspaceStore) canWatch() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.watch
+}
@@ -0,0 +1,109 @@
+package languagetool
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// AnnotatedText is a text with markup like Markdown or source code, only the
+// text parts are checked by LanguageTool. The offsets of the matches refer
+// to the whole text including the markup, see String.
+type AnnotatedText struct {
+	Annotation []Annotation `json:"annotation"`
+}
+
+// Annotation is either a part of the text to check or markup, which is
+// skipped. InterpretAs is checked instead of the markup, e.g. "\n\n" for
+// markup separating paragraphs.
+type Annotation struct {
+	Text        string `json:"text,omitempty"`
+	Markup      string `json:"markup,omitempty"`
+	InterpretAs string `json:"interpretAs,omitempty"`
+}
+
+// PlainText returns an AnnotatedText without markup.
+func PlainText(text string) AnnotatedText {
+	return AnnotatedText{Annotation: []Annotation{{Text: text}}}
+}
+
+// String returns the whole text including the markup.
+func (a AnnotatedText) String() string {
+	b := strings.Builder{}
+	for _, annotation := range a.Annotation {
+		b.WriteString(annotation.Text)
+		b.WriteString(annotation.Markup)
+	}
+	return b.String()
+}
+
+// IsPlain reports whether the text has no markup.
+func (a AnnotatedText) IsPlain() bool {
+	for _, annotation := range a.Annotation {
+		if annotation.Markup != "" || annotation.InterpretAs != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Prose returns the text LanguageTool checks, the markup is replaced by
+// its interpretation.
+func (a AnnotatedText) Prose() string {
+	b := strings.Builder{}
+	for _, annotation := range a.Annotation {
+		b.WriteString(annotation.Text)
+		b.WriteString(annotation.InterpretAs)
+	}
+	return b.String()
+}
+
+// Slice returns the part between the byte offsets start and end of String.
+// Markup cut apart keeps its interpretation in the first part.
+func (a AnnotatedText) Slice(start int, end int) AnnotatedText {
+	result := AnnotatedText{Annotation: []Annotation{}}
+	offset := 0
+	for _, annotation := range a.Annotation {
+		length := len(annotation.Text) + len(annotation.Markup)
+		from, to := max(start-offset, 0), min(end-offset, length)
+		if from < to {
+			part := Annotation{}
+			if annotation.Markup != "" {
+				part.Markup = annotation.Markup[from:to]
+				if from == 0 {
+					part.InterpretAs = annotation.InterpretAs
+				}
+			} else {
+				part.Text = annotation.Text[from:to]
+			}
+			result.Annotation = append(result.Annotation, part)
+		}
+		offset += length
+		if offset >= end {
+			break
+		}
+	}
+	return result
+}
+
+// Append returns the concatenation of both texts.
+func (a AnnotatedText) Append(other AnnotatedText) AnnotatedText {
+	annotation := make([]Annotation, 0, len(a.Annotation)+len(other.Annotation))
+	annotation = append(annotation, a.Annotation...)
+	return AnnotatedText{Annotation: append(annotation, other.Annotation...)}
+}
+
+// payloadSize returns the size of the parameter sent for the text.
+func payloadSize(text AnnotatedText) int {
+	_, value := text.param()
+	return len(value)
+}
+
+// param returns the form parameter of the /check endpoint for the text:
+// "text" for plain text and "data" otherwise.
+func (a AnnotatedText) param() (string, string) {
+	if a.IsPlain() {
+		return "text", a.String()
+	}
+	data, _ := json.Marshal(a)
+	return "data", string(data)
+}
@@ -0,0 +1,90 @@
+package languagetool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnnotatedTextSlice(t *testing.T) {
+	text := AnnotatedText{Annotation: []Annotation{
+		{Text: "Ein "},
+		{Markup: "**"},
+		{Text: "fetter"},
+		{Markup: "**"},
+		{Text: " Text.\n\n"},
+		{Markup: "```\ncode\n```\n", InterpretAs: "\n\n"},
+	}}
+
+	if whole := text.String(); whole != "Ein **fetter** Text.\n\n```\ncode\n```\n" {
+		t.Fatalf("wrong text: %q", whole)
+	}
+	if prose := text.Prose(); prose != "Ein fetter Text.\n\n\n\n" {
+		t.Fatalf("wrong prose: %q", prose)
+	}
+
+	tests := []struct {
+		start, end int
+		expect     []Annotation
+	}{
+		{start: 0, end: 22, expect: text.Annotation[:5]},
+		{start: 5, end: 9, expect: []Annotation{{Markup: "*"}, {Text: "fet"}}},
+		{start: 22, end: 26, expect: []Annotation{{Markup: "```\n", InterpretAs: "\n\n"}}},
+		{start: 26, end: 35, expect: []Annotation{{Markup: "code\n```\n"}}},
+	}
+	for _, test := range tests {
+		slice := text.Slice(test.start, test.end)
+		if !reflect.DeepEqual(slice.Annotation, test.expect) {
+			t.Fatalf("wrong slice %d:%d want: %+v, got: %+v", test.start, test.end, test.expect, slice.Annotation)
+		}
+		if slice.String() != text.String()[test.start:test.end] {
+			t.Fatalf("slice %d:%d is not lossless: %q", test.start, test.end, slice.String())
+		}
+	}
+}
+
+func TestCheckAnnotatedTextSendsData(t *testing.T) {
+	text := AnnotatedText{Annotation: []Annotation{{Markup: "# "}, {Text: "Titel"}}}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("text") != "" {
+			t.Errorf("expected no text, got: %s", r.FormValue("text"))
+		}
+		data := AnnotatedText{}
+		if err := json.Unmarshal([]byte(r.FormValue("data")), &data); err != nil || !reflect.DeepEqual(data, text) {
+			t.Errorf("wrong data want: %+v, got: %s", text, r.FormValue("data"))
+		}
+		w.Write([]byte(`{"matches": []}`))
+	})
+
+	if _, err := client.CheckAnnotatedText(context.Background(), text, CheckOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheSkipsMarkupParagraphs(t *testing.T) {
+	sent := []string{}
+	cache := NewCache(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		sent = append(sent, text)
+		return CheckResult{Matches: []Match{{Offset: 2, Length: 5}}}, nil
+	}), NewMemoryStore(100, time.Hour))
+
+	text := AnnotatedText{Annotation: []Annotation{
+		{Markup: "```\ncode\n```\n\n", InterpretAs: "\n\n"},
+		{Markup: "# "},
+		{Text: "Titel"},
+	}}
+	result, err := cache.CheckAnnotatedText(context.Background(), text, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sent, []string{"# Titel"}) {
+		t.Fatalf("expected only the heading to be sent, got: %q", sent)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].Offset != 16 {
+		t.Fatalf("wrong matches: %+v", result.Matches)
+	}
+}
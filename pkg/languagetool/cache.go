@@ -0,0 +1,143 @@
+package languagetool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Cache wraps a LanguagetoolApi and remembers the matches of every paragraph
+// by the hash of its content and the check options. Only paragraphs which
+// are not cached yet are sent to LanguageTool, the cached matches of all
+// other paragraphs are reused with shifted offsets.
+type Cache struct {
+	api   LanguagetoolApi
+	store CacheStore
+
+	mu   sync.Mutex
+	last CheckResult
+}
+
+// NewCache returns a Cache which keeps the matches in store.
+func NewCache(api LanguagetoolApi, store CacheStore) *Cache {
+	return &Cache{
+		api:   api,
+		store: store,
+	}
+}
+
+func (c *Cache) CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+	return c.CheckAnnotatedText(ctx, PlainText(text), options)
+}
+
+// CheckAnnotatedText splits the whole text including the markup into
+// paragraphs, paragraphs of markup only are not checked at all.
+func (c *Cache) CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error) {
+	paragraphs := splitParagraphs(text.String())
+	parts := make([]AnnotatedText, len(paragraphs))
+	keys := make([]string, len(paragraphs))
+	cached := make([][]Match, len(paragraphs))
+
+	misses := []int{}
+	for i, p := range paragraphs {
+		parts[i] = text.Slice(p.offset, p.offset+len(p.text))
+		if strings.TrimSpace(parts[i].Prose()) == "" {
+			continue
+		}
+		keys[i] = cacheKey(parts[i], options)
+		matches, ok := c.store.Get(keys[i])
+		if !ok {
+			misses = append(misses, i)
+			continue
+		}
+		cached[i] = matches
+	}
+
+	c.mu.Lock()
+	result := CheckResult{Software: c.last.Software, Language: c.last.Language}
+	c.mu.Unlock()
+
+	if len(misses) > 0 {
+		// check all missing paragraphs with one request
+		missing := make([]paragraph, len(misses))
+		joined := AnnotatedText{}
+		offset, offset16 := 0, 0
+		for i, index := range misses {
+			p := paragraphs[index]
+			missing[i] = paragraph{text: p.text, offset: offset, offset16: offset16}
+			joined = joined.Append(parts[index])
+			offset += len(p.text)
+			offset16 += utf16Len(p.text)
+		}
+
+		checked, err := c.api.CheckAnnotatedText(ctx, joined, options)
+		if err != nil {
+			return CheckResult{}, err
+		}
+		result.Software = checked.Software
+		result.Language = checked.Language
+		result.Warnings = checked.Warnings
+
+		matches := splitMatches(missing, checked.Matches)
+		for i, index := range misses {
+			cached[index] = matches[i]
+			// an incomplete result may lack matches, so it's not worth keeping
+			if !checked.Warnings.IncompleteResults {
+				c.store.Put(keys[index], matches[i])
+			}
+		}
+
+		c.mu.Lock()
+		c.last = checked
+		c.mu.Unlock()
+	}
+
+	result.Matches = []Match{}
+	for i, p := range paragraphs {
+		for _, match := range cached[i] {
+			match.Offset += p.offset16
+			result.Matches = append(result.Matches, match)
+		}
+	}
+	return result, nil
+}
+
+// Clear removes all cached paragraphs.
+func (c *Cache) Clear() error {
+	return c.store.Clear()
+}
+
+// cacheKey hashes the check options and the text. Plain text is hashed as
+// is, so the keys of plain paragraphs and annotated ones never collide.
+func cacheKey(text AnnotatedText, options CheckOptions) string {
+	param, value := text.param()
+	hash := sha256.New()
+	hash.Write([]byte(options.values().Encode()))
+	hash.Write([]byte{0})
+	if param != "text" {
+		hash.Write([]byte(param))
+		hash.Write([]byte{0})
+	}
+	hash.Write([]byte(value))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// splitMatches assigns the matches to the paragraph they start in and makes
+// their offsets relative to it.
+func splitMatches(paragraphs []paragraph, matches []Match) [][]Match {
+	result := make([][]Match, len(paragraphs))
+	for _, match := range matches {
+		i := sort.Search(len(paragraphs), func(i int) bool {
+			return paragraphs[i].offset16 > match.Offset
+		}) - 1
+		if i < 0 {
+			i = 0
+		}
+		match.Offset -= paragraphs[i].offset16
+		result[i] = append(result[i], match)
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package languagetool
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheChecksOnlyChangedParagraphs(t *testing.T) {
+	sent := []string{}
+	cache := NewCache(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		sent = append(sent, text)
+		result := CheckResult{}
+		for _, offset := range offsets16(text, "Fehlr") {
+			result.Matches = append(result.Matches, Match{Offset: offset, Length: 5})
+		}
+		return result, nil
+	}), NewMemoryStore(100, time.Hour))
+
+	texts := []string{
+		"Erster Fehlr.\n\nZweiter Absatz.\n\nDritter Fehlr.",
+		"Erster Fehlr.\n\nZweiter Absatz mit Fehlr und Ümlaut 😀.\n\nDritter Fehlr.",
+		"Erster Fehlr.\n\nZweiter Absatz mit Fehlr und Ümlaut 😀.\n\nDritter Fehlr.",
+	}
+	expectSent := []string{
+		texts[0],
+		"Zweiter Absatz mit Fehlr und Ümlaut 😀.\n\n",
+	}
+
+	for _, text := range texts {
+		result, err := cache.CheckText(context.Background(), text, CheckOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expect := offsets16(text, "Fehlr")
+		if len(result.Matches) != len(expect) {
+			t.Fatalf("wrong number of matches want: %d, got: %d", len(expect), len(result.Matches))
+		}
+		for i, match := range result.Matches {
+			if match.Offset != expect[i] {
+				t.Fatalf("wrong offset of match %d in %q want: %d, got: %d", i, text, expect[i], match.Offset)
+			}
+		}
+	}
+
+	if strings.Join(sent, "|") != strings.Join(expectSent, "|") {
+		t.Fatalf("wrong texts sent want: %q, got: %q", expectSent, sent)
+	}
+}
+
+func TestCacheKeysContainOptions(t *testing.T) {
+	calls := 0
+	cache := NewCache(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		calls++
+		return CheckResult{}, nil
+	}), NewMemoryStore(100, time.Hour))
+
+	cache.CheckText(context.Background(), "Text", CheckOptions{})
+	cache.CheckText(context.Background(), "Text", CheckOptions{Level: "picky"})
+	cache.CheckText(context.Background(), "Text", CheckOptions{Level: "picky"})
+
+	if calls != 2 {
+		t.Fatalf("wrong number of calls want: 2, got: %d", calls)
+	}
+}
+
+func TestCacheEvicts(t *testing.T) {
+	calls := 0
+	cache := NewCache(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		calls++
+		return CheckResult{}, nil
+	}), NewMemoryStore(1, time.Hour))
+
+	for _, text := range []string{"a", "b", "a"} {
+		cache.CheckText(context.Background(), text, CheckOptions{})
+	}
+	if calls != 3 {
+		t.Fatalf("wrong number of calls want: 3, got: %d", calls)
+	}
+
+	cache = NewCache(cache.api, NewMemoryStore(10, -time.Second))
+	calls = 0
+	for _, text := range []string{"a", "a"} {
+		cache.CheckText(context.Background(), text, CheckOptions{})
+	}
+	if calls != 2 {
+		t.Fatalf("expired entries were used, calls want: 2, got: %d", calls)
+	}
+}
@@ -0,0 +1,136 @@
+package languagetool
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CacheStore keeps the matches of paragraphs for a Cache.
+type CacheStore interface {
+	Get(key string) ([]Match, bool)
+	Put(key string, matches []Match)
+	Clear() error
+}
+
+type cacheEntry struct {
+	key     string
+	matches []Match
+	expires time.Time
+	used    time.Time
+}
+
+// MemoryStore is a CacheStore which holds the size least recently used
+// paragraphs for ttl.
+type MemoryStore struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func NewMemoryStore(size int, ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (m *MemoryStore) Get(key string) ([]Match, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		m.order.Remove(element)
+		delete(m.entries, key)
+		return nil, false
+	}
+	entry.used = time.Now()
+	m.order.MoveToFront(element)
+	return entry.matches, true
+}
+
+func (m *MemoryStore) Put(key string, matches []Match) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.set(&cacheEntry{key: key, matches: matches, expires: now.Add(m.ttl), used: now})
+	m.evict()
+}
+
+func (m *MemoryStore) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = map[string]*list.Element{}
+	m.order.Init()
+	return nil
+}
+
+// snapshot returns all entries which are not expired yet.
+func (m *MemoryStore) snapshot() []cacheEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entries := []cacheEntry{}
+	for element := m.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*cacheEntry)
+		if now.Before(entry.expires) {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// merge adds the entries which were used more recently than the ones in the
+// store.
+func (m *MemoryStore) merge(entries []cacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range entries {
+		if element, ok := m.entries[entry.key]; ok && !element.Value.(*cacheEntry).used.Before(entry.used) {
+			continue
+		}
+		entry := entry
+		m.set(&entry)
+	}
+
+	// restore the order after entries were added in any order
+	all := make([]*cacheEntry, 0, m.order.Len())
+	for element := m.order.Front(); element != nil; element = element.Next() {
+		all = append(all, element.Value.(*cacheEntry))
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].used.After(all[j].used) })
+	m.order.Init()
+	for _, entry := range all {
+		m.entries[entry.key] = m.order.PushBack(entry)
+	}
+	m.evict()
+}
+
+func (m *MemoryStore) set(entry *cacheEntry) {
+	if element, ok := m.entries[entry.key]; ok {
+		m.order.Remove(element)
+	}
+	m.entries[entry.key] = m.order.PushFront(entry)
+}
+
+func (m *MemoryStore) evict() {
+	for m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,166 @@
+package languagetool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Chunker wraps a LanguagetoolApi and splits texts larger than maxBytes on
+// paragraph boundaries (or lines and sentences if a paragraph is too large).
+// The chunks are checked separately and the offsets of the matches are
+// mapped back onto the whole text.
+type Chunker struct {
+	api      LanguagetoolApi
+	maxBytes int
+	parallel int
+}
+
+// NewChunker returns a Chunker which checks up to parallel chunks at once.
+func NewChunker(api LanguagetoolApi, maxBytes int, parallel int) *Chunker {
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &Chunker{
+		api:      api,
+		maxBytes: maxBytes,
+		parallel: parallel,
+	}
+}
+
+func (c *Chunker) CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+	return c.CheckAnnotatedText(ctx, PlainText(text), options)
+}
+
+// CheckAnnotatedText splits the whole text including the markup. The size
+// of a chunk is the size of the parameter sent for it, which is the JSON
+// encoded data for texts with markup.
+func (c *Chunker) CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error) {
+	if c.maxBytes <= 0 || payloadSize(text) <= c.maxBytes {
+		return c.api.CheckAnnotatedText(ctx, text, options)
+	}
+
+	chunks := c.chunks(text)
+	results := make([]CheckResult, len(chunks))
+	errs := make([]error, len(chunks))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, c.parallel)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk paragraph) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			results[i], errs[i] = c.api.CheckAnnotatedText(ctx, text.Slice(chunk.offset, chunk.offset+len(chunk.text)), options)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	// report the error which caused the cancellation of the other chunks
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return CheckResult{}, firstErr
+	}
+
+	return mergeResults(chunks, results), nil
+}
+
+// chunks packs the paragraphs of text into chunks of at most maxBytes. A
+// chunk is measured as a whole, a plain part joined with markup is sent as
+// JSON and its escaped characters grow.
+func (c *Chunker) chunks(text AnnotatedText) []paragraph {
+	whole := text.String()
+	chunks := []paragraph{}
+	current := paragraph{}
+	for _, p := range c.parts(text, whole) {
+		if current.text == "" {
+			current = p
+			continue
+		}
+		if payloadSize(text.Slice(current.offset, p.offset+len(p.text))) > c.maxBytes {
+			chunks = append(chunks, current)
+			current = p
+			continue
+		}
+		current.text = whole[current.offset : p.offset+len(p.text)]
+	}
+	if current.text != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// parts splits the whole text into paragraphs and those which are larger
+// than maxBytes into lines, sentences and at last bytes.
+func (c *Chunker) parts(text AnnotatedText, whole string) []paragraph {
+	size := func(p paragraph) int {
+		return payloadSize(text.Slice(p.offset, p.offset+len(p.text)))
+	}
+	splits := []func(paragraph) []paragraph{
+		func(p paragraph) []paragraph { return splitParagraphs(p.text) },
+		func(p paragraph) []paragraph { return splitLines(p.text) },
+		func(p paragraph) []paragraph { return splitSentences(p.text) },
+		// markup and escaping make the data larger than the text, the
+		// parts are split again until they fit
+		func(p paragraph) []paragraph {
+			return splitBytes(p.text, max(c.maxBytes*len(p.text)/size(p), 1))
+		},
+	}
+
+	var split func(p paragraph, level int) []paragraph
+	split = func(p paragraph, level int) []paragraph {
+		if level == len(splits) || size(p) <= c.maxBytes {
+			return []paragraph{p}
+		}
+		parts := splits[level](p)
+		next := level + 1
+		if next == len(splits) && len(parts) > 1 {
+			next = level
+		}
+		result := []paragraph{}
+		for _, part := range parts {
+			part.offset += p.offset
+			part.offset16 += p.offset16
+			result = append(result, split(part, next)...)
+		}
+		return result
+	}
+
+	return split(paragraph{text: whole}, 0)
+}
+
+// mergeResults combines the results of the chunks and maps the offsets of
+// the matches onto the whole text.
+func mergeResults(chunks []paragraph, results []CheckResult) CheckResult {
+	merged := CheckResult{Matches: []Match{}}
+	for i, result := range results {
+		if i == 0 {
+			merged.Software = result.Software
+			merged.Language = result.Language
+		}
+		merged.Warnings.IncompleteResults = merged.Warnings.IncompleteResults || result.Warnings.IncompleteResults
+		for _, match := range result.Matches {
+			match.Offset += chunks[i].offset16
+			merged.Matches = append(merged.Matches, match)
+		}
+	}
+	return merged
+}
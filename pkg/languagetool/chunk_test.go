@@ -0,0 +1,150 @@
+package languagetool
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChunkerRemapsOffsets(t *testing.T) {
+	text := "Ein Satz mit Fehlr.\n\nÄrger 😀 über Fehlr.\n\nNoch ein Fehlr."
+
+	mu := sync.Mutex{}
+	chunks := []string{}
+	chunker := NewChunker(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		mu.Lock()
+		chunks = append(chunks, text)
+		mu.Unlock()
+
+		result := CheckResult{}
+		for _, offset := range offsets16(text, "Fehlr") {
+			result.Matches = append(result.Matches, Match{Offset: offset, Length: 5})
+		}
+		return result, nil
+	}), 25, 2)
+
+	result, err := chunker.CheckText(context.Background(), text, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("wrong number of chunks want: 3, got: %d (%q)", len(chunks), chunks)
+	}
+
+	expect := offsets16(text, "Fehlr")
+	if len(result.Matches) != len(expect) {
+		t.Fatalf("wrong number of matches want: %d, got: %d", len(expect), len(result.Matches))
+	}
+	for i, match := range result.Matches {
+		if match.Offset != expect[i] {
+			t.Fatalf("wrong offset of match %d want: %d, got: %d", i, expect[i], match.Offset)
+		}
+	}
+}
+
+func TestChunkerSplitsLargeParagraphs(t *testing.T) {
+	text := strings.Repeat("Das ist ein Satz. ", 10) + "\n" + strings.Repeat("ü", 40)
+
+	chunker := NewChunker(nil, 30, 1)
+	joined := ""
+	for _, chunk := range chunker.chunks(PlainText(text)) {
+		if len(chunk.text) > 30 {
+			t.Fatalf("chunk is larger than 30 bytes: %q", chunk.text)
+		}
+		if chunk.offset != len(joined) || chunk.offset16 != utf16Len(joined) {
+			t.Fatalf("wrong offset of chunk %q", chunk.text)
+		}
+		joined += chunk.text
+	}
+	if joined != text {
+		t.Fatalf("chunks don't add up to the text: %q", joined)
+	}
+}
+
+type annotatedFunc func(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error)
+
+func (f annotatedFunc) CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+	return f(ctx, PlainText(text), options)
+}
+
+func (f annotatedFunc) CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error) {
+	return f(ctx, text, options)
+}
+
+func TestChunkerMeasuresData(t *testing.T) {
+	// the markup is escaped in JSON, the data is much larger than the text
+	text := AnnotatedText{}
+	for i := 0; i < 20; i++ {
+		text = text.Append(AnnotatedText{Annotation: []Annotation{
+			{Markup: "<a href=\"x\">", InterpretAs: "\"Link\""},
+			{Text: "Satz mit Fehlr."},
+			{Markup: "</a>\n\n", InterpretAs: "\n\n"},
+		}})
+	}
+	maxBytes := len(text.String()) + 10
+
+	mu := sync.Mutex{}
+	joined := ""
+	chunker := NewChunker(annotatedFunc(func(ctx context.Context, chunk AnnotatedText, options CheckOptions) (CheckResult, error) {
+		if size := payloadSize(chunk); size > maxBytes {
+			t.Errorf("data of chunk is larger than %d bytes: %d", maxBytes, size)
+		}
+		mu.Lock()
+		joined += chunk.String()
+		mu.Unlock()
+		return CheckResult{}, nil
+	}), maxBytes, 1)
+
+	if _, err := chunker.CheckAnnotatedText(context.Background(), text, CheckOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if joined != text.String() {
+		t.Fatalf("chunks don't add up to the text: %q", joined)
+	}
+
+	// a single paragraph is split even within the markup
+	chunker.maxBytes = 80
+	for _, chunk := range chunker.chunks(text) {
+		if size := payloadSize(text.Slice(chunk.offset, chunk.offset+len(chunk.text))); size > 80 {
+			t.Fatalf("data of chunk %q is larger than 80 bytes: %d", chunk.text, size)
+		}
+	}
+
+	// plain paragraphs are small as text, but escaped once they are joined
+	// with markup
+	mixed := PlainText(strings.Repeat("a < b & c. ", 6) + "\n\n")
+	mixed = mixed.Append(AnnotatedText{Annotation: []Annotation{
+		{Text: "Use "}, {Markup: "`go vet`", InterpretAs: "go vet"}, {Text: " first.\n\n"},
+	}})
+	for i := 0; i < 3; i++ {
+		mixed = mixed.Append(PlainText(strings.Repeat("a < b & c. ", 6) + "\n\n"))
+	}
+	for _, maxBytes := range []int{170, 250} {
+		chunker.maxBytes = maxBytes
+		joined := ""
+		for _, chunk := range chunker.chunks(mixed) {
+			if size := payloadSize(mixed.Slice(chunk.offset, chunk.offset+len(chunk.text))); size > maxBytes {
+				t.Fatalf("data of chunk %q is larger than %d bytes: %d", chunk.text, maxBytes, size)
+			}
+			joined += chunk.text
+		}
+		if joined != mixed.String() {
+			t.Fatalf("chunks don't add up to the text: %q", joined)
+		}
+	}
+}
+
+// offsets16 returns the offsets of word in text like LanguageTool counts them.
+func offsets16(text string, word string) []int {
+	offsets := []int{}
+	for i := 0; ; i++ {
+		next := strings.Index(text[i:], word)
+		if next < 0 {
+			return offsets
+		}
+		i += next
+		offsets = append(offsets, utf16Len(text[:i]))
+	}
+}
@@ -6,16 +6,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
 
-	"strconv"
-
 	"go.uber.org/zap"
 )
 
 type LanguagetoolApi interface {
-	CheckText(ctx context.Context, text string) (CheckResult, error)
+	CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error)
+	CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error)
 }
 
 type Credentials struct {
@@ -23,6 +21,14 @@ type Credentials struct {
 	ApiToken string
 }
 
+const (
+	// DefaultBaseURL is the endpoint of the hosted LanguageTool (premium) API.
+	DefaultBaseURL = "https://api.languagetoolplus.com/v2/"
+	// PublicBaseURL is the endpoint of the free LanguageTool API, which
+	// doesn't need credentials.
+	PublicBaseURL = "https://api.languagetool.org/v2/"
+)
+
 type Client struct {
 	log         *zap.Logger
 	baseURL     string
@@ -32,7 +38,7 @@ type Client struct {
 
 func NewClient(logger *zap.Logger) *Client {
 	return &Client{
-		baseURL:     "https://api.languagetoolplus.com/v2/",
+		baseURL:     DefaultBaseURL,
 		client:      &http.Client{},
 		log:         logger,
 		credentials: Credentials{},
@@ -48,26 +54,94 @@ func (c Client) WithCredentials(credentials Credentials) *Client {
 	}
 }
 
+// WithBaseURL points the client to another LanguageTool server, e.g. a
+// self-hosted instance like http://localhost:8081/v2/. An empty baseURL keeps
+// the current one.
+func (c Client) WithBaseURL(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = c.baseURL
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &Client{
+		baseURL:     baseURL,
+		client:      c.client,
+		log:         c.log,
+		credentials: c.credentials,
+	}
+}
+
+// BaseURL returns the endpoint the client sends its requests to.
+func (c Client) BaseURL() string {
+	return c.baseURL
+}
+
+// IsSelfHosted reports whether the client talks to a server other than the
+// hosted LanguageTool APIs.
+func (c Client) IsSelfHosted() bool {
+	return c.baseURL != DefaultBaseURL && c.baseURL != PublicBaseURL
+}
+
+// IsPublic reports whether the client talks to the free LanguageTool API.
+func (c Client) IsPublic() bool {
+	return c.baseURL == PublicBaseURL
+}
+
 type CheckResult struct {
 	Software Software `json:"software"`
+	Warnings Warnings `json:"warnings"`
+	Language Language `json:"language"`
 	Matches  []Match  `json:"matches"`
 }
 
 type Software struct {
-	Premium bool `json:"premium"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	BuildDate  string `json:"buildDate"`
+	ApiVersion int    `json:"apiVersion"`
+	Premium    bool   `json:"premium"`
+	Status     string `json:"status"`
+}
+
+type Warnings struct {
+	// IncompleteResults is set if the server stopped checking, e.g. because of a timeout.
+	IncompleteResults bool `json:"incompleteResults"`
+}
+
+type Language struct {
+	Name             string           `json:"name"`
+	Code             string           `json:"code"`
+	DetectedLanguage DetectedLanguage `json:"detectedLanguage"`
+}
+
+type DetectedLanguage struct {
+	Name       string  `json:"name"`
+	Code       string  `json:"code"`
+	Confidence float64 `json:"confidence"`
 }
 
 type Match struct {
 	Message      string        `json:"message"`
+	ShortMessage string        `json:"shortMessage"`
 	Offset       int           `json:"offset"`
 	Length       int           `json:"length"`
 	Context      MatchContext  `json:"context"`
 	Sentence     string        `json:"sentence"`
 	Replacements []Replacement `json:"replacements"`
+	Type         MatchType     `json:"type"`
+	Rule         Rule          `json:"rule"`
+	// ContextForSureMatch is the number of words needed around the match to
+	// be sure about it, -1 if the whole sentence is needed.
+	ContextForSureMatch int `json:"contextForSureMatch"`
+	// IgnoreForIncompleteSentence is set if the match may be a false alarm
+	// because the sentence is not finished yet.
+	IgnoreForIncompleteSentence bool `json:"ignoreForIncompleteSentence"`
 }
 
 type Replacement struct {
-	Value string `json:"value"`
+	Value            string `json:"value"`
+	ShortDescription string `json:"shortDescription,omitempty"`
 }
 
 type MatchContext struct {
@@ -76,19 +150,51 @@ type MatchContext struct {
 	Length int    `json:"length"`
 }
 
-func (c Client) CheckText(ctx context.Context, text string) (CheckResult, error) {
-	c.log.Debug(text)
-	c.log.Debug(fmt.Sprintf("%d", len(text)))
+type MatchType struct {
+	// TypeName is one of "UnknownWord", "Hint" or "Other".
+	TypeName string `json:"typeName"`
+}
+
+type Rule struct {
+	ID          string `json:"id"`
+	SubID       string `json:"subId,omitempty"`
+	Description string `json:"description"`
+	URLs        []URL  `json:"urls,omitempty"`
+	// IssueType is a localization quality issue type like "misspelling",
+	// "grammar", "style", "typographical" or "whitespace".
+	IssueType string   `json:"issueType"`
+	Category  Category `json:"category"`
+}
+
+type URL struct {
+	Value string `json:"value"`
+}
+
+type Category struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c Client) CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+	return c.CheckAnnotatedText(ctx, PlainText(text), options)
+}
+
+// CheckAnnotatedText checks the text parts of text, plain text is sent as
+// the text parameter and everything else as data.
+func (c Client) CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error) {
+	param, value := text.param()
+	c.log.Debug(value)
+	c.log.Debug(fmt.Sprintf("%d", len(value)))
 
 	result := CheckResult{}
 	fullUrl := c.baseURL + "check"
 
-	formData := url.Values{
-		"text":        {text},
-		"username":    {c.credentials.Username},
-		"apiKey":      {c.credentials.ApiToken},
-		"language":    {"auto"},
-		"enabledOnly": {strconv.FormatBool(false)},
+	formData := options.values()
+	formData.Set(param, value)
+	// self-hosted servers usually run without authentication
+	if c.credentials.Username != "" {
+		formData.Set("username", c.credentials.Username)
+		formData.Set("apiKey", c.credentials.ApiToken)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullUrl, strings.NewReader(formData.Encode()))
@@ -102,18 +208,28 @@ func (c Client) CheckText(ctx context.Context, text string) (CheckResult, error)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.client.Do(req)
-	c.log.Debug(resp.Status)
 	if err != nil {
 		c.log.Error(err.Error())
 		return result, err
 	}
 	defer resp.Body.Close()
+	c.log.Debug(resp.Status)
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		c.log.Error(err.Error())
 		return result, err
 	}
-	err = json.Unmarshal(body, &result)
-	return result, nil
 
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError(resp, body)
+		c.log.Error(apiErr.Error())
+		return result, apiErr
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		c.log.Error(err.Error())
+		return result, fmt.Errorf("languagetool: %w: %w", ErrDecode, err)
+	}
+	return result, nil
 }
@@ -0,0 +1,166 @@
+package languagetool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(zap.NewNop()).WithBaseURL(server.URL + "/v2")
+}
+
+func TestCheckTextDecodesResponse(t *testing.T) {
+	response, err := os.ReadFile("testdata/check.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/check" {
+			t.Errorf("wrong path want: /v2/check, got: %s", r.URL.Path)
+		}
+		if got := r.FormValue("language"); got != "de-DE" {
+			t.Errorf("wrong language want: de-DE, got: %s", got)
+		}
+		if got := r.FormValue("level"); got != "picky" {
+			t.Errorf("wrong level want: picky, got: %s", got)
+		}
+		w.Write(response)
+	})
+
+	result, err := client.CheckText(context.Background(), "Apffelstaft ist lecker.", CheckOptions{Language: "de-DE", Level: "picky"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Software.Premium || result.Language.DetectedLanguage.Code != "de-DE" {
+		t.Fatalf("wrong software or language: %+v", result)
+	}
+
+	expect := Match{
+		Message:      "Möglicher Tippfehler gefunden.",
+		ShortMessage: "Rechtschreibfehler",
+		Offset:       0,
+		Length:       11,
+		Context:      MatchContext{Text: "Apffelstaft ist lecker.", Offset: 0, Length: 11},
+		Sentence:     "Apffelstaft ist lecker.",
+		Replacements: []Replacement{{Value: "Apfelsaft"}, {Value: "Apfelstadt"}},
+		Type:         MatchType{TypeName: "UnknownWord"},
+		Rule: Rule{
+			ID:          "GERMAN_SPELLER_RULE",
+			Description: "Möglicher Rechtschreibfehler",
+			URLs:        []URL{{Value: "https://languagetool.org/insights/de/beitrag/rechtschreibung/"}},
+			IssueType:   "misspelling",
+			Category:    Category{ID: "TYPOS", Name: "Mögliche Tippfehler"},
+		},
+	}
+	if len(result.Matches) != 1 || !reflect.DeepEqual(result.Matches[0], expect) {
+		t.Fatalf("wrong matches want: %+v, got: %+v", expect, result.Matches)
+	}
+}
+
+func TestCheckTextErrors(t *testing.T) {
+	tests := []struct {
+		status     int
+		header     http.Header
+		body       string
+		expect     error
+		retryAfter time.Duration
+	}{
+		{status: http.StatusUnauthorized, expect: ErrUnauthorized},
+		{status: http.StatusForbidden, expect: ErrUnauthorized},
+		{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"30"}}, expect: ErrRateLimited, retryAfter: 30 * time.Second},
+		{status: http.StatusRequestEntityTooLarge, expect: ErrPayloadTooLarge},
+		{status: http.StatusBadRequest, body: "Error: 'xx-XX' is not a language code known to LanguageTool.", expect: ErrBadRequest},
+		{status: http.StatusInternalServerError, expect: ErrServer},
+		{status: http.StatusBadGateway, expect: ErrServer},
+		{status: http.StatusOK, body: "<html>", expect: ErrDecode},
+	}
+
+	for _, test := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			for key, values := range test.header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		})
+
+		_, err := client.CheckText(context.Background(), "text", CheckOptions{})
+		if !errors.Is(err, test.expect) {
+			t.Fatalf("wrong error for status %d want: %v, got: %v", test.status, test.expect, err)
+		}
+
+		retryAfter, _ := RetryAfter(err)
+		if retryAfter != test.retryAfter {
+			t.Fatalf("wrong retry after for status %d want: %s, got: %s", test.status, test.retryAfter, retryAfter)
+		}
+	}
+}
+
+func TestCheckTextOffline(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := NewClient(zap.NewNop()).WithBaseURL(server.URL)
+	if _, err := client.CheckText(context.Background(), "text", CheckOptions{}); err == nil {
+		t.Fatal("expected an error for an unreachable server")
+	}
+}
+
+func TestAddWord(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/words/add" {
+			t.Errorf("wrong path want: /v2/words/add, got: %s", r.URL.Path)
+		}
+		if got := r.FormValue("word"); got != "Gopher" {
+			t.Errorf("wrong word want: Gopher, got: %s", got)
+		}
+		if got := r.FormValue("username"); got != "user" {
+			t.Errorf("wrong username want: user, got: %s", got)
+		}
+		w.Write([]byte(`{"added": true}`))
+	})
+
+	if err := client.AddWord(context.Background(), "Gopher"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized without credentials, got: %v", err)
+	}
+
+	client = client.WithCredentials(Credentials{Username: "user", ApiToken: "token"})
+	if err := client.AddWord(context.Background(), "Gopher"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientHost(t *testing.T) {
+	tests := []struct {
+		baseURL    string
+		selfHosted bool
+		public     bool
+	}{
+		{baseURL: "", selfHosted: false, public: false},
+		{baseURL: "https://api.languagetool.org/v2", selfHosted: false, public: true},
+		{baseURL: "http://localhost:8081/v2/", selfHosted: true, public: false},
+	}
+
+	for _, test := range tests {
+		client := NewClient(zap.NewNop()).WithBaseURL(test.baseURL)
+		if got := client.IsSelfHosted(); got != test.selfHosted {
+			t.Errorf("%q: wrong self-hosted want: %t, got: %t", test.baseURL, test.selfHosted, got)
+		}
+		if got := client.IsPublic(); got != test.public {
+			t.Errorf("%q: wrong public want: %t, got: %t", test.baseURL, test.public, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package languagetool
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized    = errors.New("invalid username or api key")
+	ErrRateLimited     = errors.New("rate limit exceeded")
+	ErrPayloadTooLarge = errors.New("text is too large")
+	ErrBadRequest      = errors.New("invalid request")
+	ErrServer          = errors.New("server error")
+	ErrDecode          = errors.New("could not decode response")
+)
+
+// APIError is returned if the server answered with another status than
+// 200 OK. It wraps one of the Err* errors, so it can be checked with
+// errors.Is.
+type APIError struct {
+	StatusCode int
+	// Message is the body of the response, LanguageTool sends plain text errors.
+	Message string
+	// RetryAfter is the time the server asked to wait before the next request.
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("languagetool: %s (%d)", e.err, e.StatusCode)
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+	return message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// RetryAfter returns the time the server asked to wait if err is an APIError
+// with a Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		apiErr.err = ErrUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.err = ErrRateLimited
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		apiErr.err = ErrPayloadTooLarge
+	case resp.StatusCode >= 500:
+		apiErr.err = ErrServer
+	default:
+		apiErr.err = ErrBadRequest
+	}
+	return apiErr
+}
+
+// parseRetryAfter supports both forms of the header, seconds and a http date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
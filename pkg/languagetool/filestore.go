@@ -0,0 +1,315 @@
+package languagetool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileStoreVersion has to be increased whenever the format of the file or
+// of the cached matches changes, older files are ignored then.
+const fileStoreVersion = 1
+
+const (
+	// fileStoreSaveInterval is the delay of saving after a change, the
+	// changes in the meantime are saved at once.
+	fileStoreSaveInterval = 10 * time.Second
+	// fileStoreReloadInterval is the minimum time between two checks of
+	// the file for changes of other processes.
+	fileStoreReloadInterval = 5 * time.Second
+	fileStoreLockTimeout    = time.Second
+	fileStoreStaleLock      = 10 * time.Second
+)
+
+type fileStoreContent struct {
+	Version int                       `json:"version"`
+	Entries map[string]fileStoreEntry `json:"entries"`
+}
+
+type fileStoreEntry struct {
+	Matches []Match   `json:"matches"`
+	Expires time.Time `json:"expires"`
+	Used    time.Time `json:"used"`
+}
+
+// FileStore is a CacheStore which persists the cached paragraphs in a file,
+// so they survive a restart of the editor. Several processes can share the
+// file: before saving, the entries of the file are merged with the own
+// ones, and a miss reloads the file if another process changed it. Changes
+// are saved in the background, Close saves the remaining ones.
+type FileStore struct {
+	log            *zap.Logger
+	path           string
+	memory         *MemoryStore
+	reloadInterval time.Duration
+
+	// mu guards the file.
+	mu       sync.Mutex
+	modified time.Time
+	checked  time.Time
+
+	// pending guards the scheduled save.
+	pending sync.Mutex
+	dirty   bool
+	timer   *time.Timer
+	closed  bool
+}
+
+// DefaultCacheFile returns the cache file in the XDG cache directory.
+func DefaultCacheFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lt-lsp", "cache.json"), nil
+}
+
+// NewFileStore loads the cache from path, it holds at most size paragraphs
+// for ttl.
+func NewFileStore(log *zap.Logger, path string, size int, ttl time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &FileStore{
+		log:            log,
+		path:           path,
+		memory:         NewMemoryStore(size, ttl),
+		reloadInterval: fileStoreReloadInterval,
+	}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileStore) Get(key string) ([]Match, bool) {
+	if matches, ok := f.memory.Get(key); ok {
+		return matches, true
+	}
+	if err := f.reloadIfDue(); err != nil {
+		f.log.Error(err.Error())
+	}
+	return f.memory.Get(key)
+}
+
+// Put caches the matches and schedules saving the file, it never waits for
+// the file.
+func (f *FileStore) Put(key string, matches []Match) {
+	f.memory.Put(key, matches)
+
+	f.pending.Lock()
+	defer f.pending.Unlock()
+	f.dirty = true
+	if f.timer == nil && !f.closed {
+		f.timer = time.AfterFunc(fileStoreSaveInterval, func() {
+			if err := f.Save(); err != nil {
+				f.log.Error(err.Error())
+			}
+		})
+	}
+}
+
+func (f *FileStore) Clear() error {
+	f.memory.Clear()
+	f.takePending()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f.modified = time.Time{}
+	return nil
+}
+
+// Close saves the cache if it was changed since the last save, later
+// changes are not saved anymore.
+func (f *FileStore) Close() error {
+	f.pending.Lock()
+	f.closed = true
+	f.pending.Unlock()
+
+	if !f.takePending() {
+		return nil
+	}
+	return f.save()
+}
+
+// Save merges the cache with the file and writes it.
+func (f *FileStore) Save() error {
+	f.takePending()
+	return f.save()
+}
+
+// takePending cancels the scheduled save and reports whether the cache was
+// changed since the last save.
+func (f *FileStore) takePending() bool {
+	f.pending.Lock()
+	defer f.pending.Unlock()
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	dirty := f.dirty
+	f.dirty = false
+	return dirty
+}
+
+func (f *FileStore) save() (err error) {
+	defer func() {
+		if err != nil {
+			// the changes are saved with the next attempt
+			f.pending.Lock()
+			f.dirty = true
+			f.pending.Unlock()
+		}
+	}()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	f.memory.merge(entries)
+
+	content := fileStoreContent{Version: fileStoreVersion, Entries: map[string]fileStoreEntry{}}
+	for _, entry := range f.memory.snapshot() {
+		content.Entries[entry.key] = fileStoreEntry{Matches: entry.matches, Expires: entry.expires, Used: entry.used}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so readers never see a partial file
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(f.path); err == nil {
+		f.modified = info.ModTime()
+	}
+	return nil
+}
+
+// reloadIfDue reloads the file unless it was checked recently or is saved
+// right now, which merges the file anyway.
+func (f *FileStore) reloadIfDue() error {
+	if !f.mu.TryLock() {
+		return nil
+	}
+	defer f.mu.Unlock()
+
+	if time.Since(f.checked) < f.reloadInterval {
+		return nil
+	}
+	return f.reloadLocked()
+}
+
+// reload merges the file into the cache if it was changed since it was read
+// the last time.
+func (f *FileStore) reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reloadLocked()
+}
+
+func (f *FileStore) reloadLocked() error {
+	f.checked = time.Now()
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(f.modified) {
+		return nil
+	}
+
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	f.memory.merge(entries)
+	f.modified = info.ModTime()
+	return nil
+}
+
+func (f *FileStore) read() ([]cacheEntry, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := fileStoreContent{}
+	if err := json.Unmarshal(data, &content); err != nil || content.Version != fileStoreVersion {
+		// the file is broken or of another version, it will be overwritten
+		f.log.Debug(fmt.Sprintf("ignoring cache file %s", f.path))
+		return nil, nil
+	}
+
+	now := time.Now()
+	entries := make([]cacheEntry, 0, len(content.Entries))
+	for key, entry := range content.Entries {
+		if now.After(entry.Expires) {
+			continue
+		}
+		entries = append(entries, cacheEntry{key: key, matches: entry.Matches, expires: entry.Expires, used: entry.Used})
+	}
+	return entries, nil
+}
+
+// lock creates a lock file to keep other processes from writing the cache
+// at the same time. Locks older than fileStoreStaleLock are left over from a
+// crashed process and are removed.
+func (f *FileStore) lock() (func(), error) {
+	path := f.path + ".lock"
+	deadline := time.Now().Add(fileStoreLockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > fileStoreStaleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("could not lock cache file %s", f.path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,133 @@
+package languagetool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestFileStoreIsShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lt-lsp", "cache.json")
+	matches := []Match{{Message: "Möglicher Tippfehler gefunden.", Offset: 3, Length: 5}}
+
+	first, err := NewFileStore(zap.NewNop(), path, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewFileStore(zap.NewNop(), path, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// check the file on every miss
+	second.reloadInterval = 0
+
+	first.Put("a", matches)
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a running instance picks up the entries of the other one
+	got, ok := second.Get("a")
+	if !ok || !reflect.DeepEqual(got, matches) {
+		t.Fatalf("wrong matches want: %+v, got: %+v", matches, got)
+	}
+
+	// and both entries survive a restart
+	second.Put("b", nil)
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+	third, err := NewFileStore(zap.NewNop(), path, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok := third.Get(key); !ok {
+			t.Fatalf("missing entry %s after restart", key)
+		}
+	}
+}
+
+func TestFileStoreEvicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	store, err := NewFileStore(zap.NewNop(), path, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		store.Put(key, nil)
+		time.Sleep(time.Millisecond)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := NewFileStore(zap.NewNop(), path, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restarted.Get("a"); ok {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	if _, ok := restarted.Get("c"); !ok {
+		t.Fatal("newest entry is missing")
+	}
+}
+
+func TestFileStoreIgnoresOtherVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	content := `{"version": 0, "entries": {"a": {"matches": [], "expires": "2999-01-01T00:00:00Z", "used": "2024-01-01T00:00:00Z"}}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(zap.NewNop(), path, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("a"); ok {
+		t.Fatal("entry of an old version was used")
+	}
+}
+
+func TestFileStoreSavesInBackground(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	store, err := NewFileStore(zap.NewNop(), path, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another process holds the lock, a put must not wait for it
+	if err := os.WriteFile(path+".lock", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	store.Put("a", nil)
+	if _, ok := store.Get("b"); ok {
+		t.Fatal("unexpected entry b")
+	}
+	if elapsed := time.Since(start); elapsed >= fileStoreLockTimeout {
+		t.Fatalf("put and get waited for the lock for %s", elapsed)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("the cache was saved on the check path")
+	}
+
+	if err := store.Close(); err == nil {
+		t.Fatal("expected close to fail while the file is locked")
+	}
+	if err := os.Remove(path + ".lock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("the cache was not saved on close: %s", err)
+	}
+}
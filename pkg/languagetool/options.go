@@ -0,0 +1,104 @@
+package languagetool
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CheckOptions are the optional parameters of the /check endpoint, see
+// https://languagetool.org/http-api/ for a detailed description.
+type CheckOptions struct {
+	// Language is a code like "en-US" or "de-DE", defaults to "auto".
+	Language string `json:"language,omitempty"`
+	// MotherTongue enables false friends checks, e.g. "de-DE".
+	MotherTongue string `json:"motherTongue,omitempty"`
+	// PreferredVariants are used when the language is detected, e.g. "en-GB".
+	PreferredVariants  []string `json:"preferredVariants,omitempty"`
+	EnabledRules       []string `json:"enabledRules,omitempty"`
+	DisabledRules      []string `json:"disabledRules,omitempty"`
+	EnabledCategories  []string `json:"enabledCategories,omitempty"`
+	DisabledCategories []string `json:"disabledCategories,omitempty"`
+	// Level is either "default" or "picky".
+	Level string `json:"level,omitempty"`
+	// EnabledOnly runs only the enabled rules and categories. It is a
+	// pointer, so a narrower configuration can turn it off again.
+	EnabledOnly *bool `json:"enabledOnly,omitempty"`
+}
+
+// Merge returns the options with other applied on top. Set values of other
+// overwrite the values of o, rule and category lists are combined.
+func (o CheckOptions) Merge(other CheckOptions) CheckOptions {
+	if other.Language != "" {
+		o.Language = other.Language
+	}
+	if other.MotherTongue != "" {
+		o.MotherTongue = other.MotherTongue
+	}
+	if len(other.PreferredVariants) > 0 {
+		o.PreferredVariants = other.PreferredVariants
+	}
+	if other.Level != "" {
+		o.Level = other.Level
+	}
+	o.EnabledRules = union(o.EnabledRules, other.EnabledRules)
+	o.DisabledRules = union(o.DisabledRules, other.DisabledRules)
+	o.EnabledCategories = union(o.EnabledCategories, other.EnabledCategories)
+	o.DisabledCategories = union(o.DisabledCategories, other.DisabledCategories)
+	if other.EnabledOnly != nil {
+		o.EnabledOnly = other.EnabledOnly
+	}
+	return o
+}
+
+func (o CheckOptions) values() url.Values {
+	language := o.Language
+	if language == "" {
+		language = "auto"
+	}
+
+	values := url.Values{
+		"language":    {language},
+		"enabledOnly": {strconv.FormatBool(o.EnabledOnly != nil && *o.EnabledOnly)},
+	}
+	setList := func(key string, list []string) {
+		if len(list) > 0 {
+			values.Set(key, strings.Join(list, ","))
+		}
+	}
+	if o.MotherTongue != "" {
+		values.Set("motherTongue", o.MotherTongue)
+	}
+	// the api rejects preferred variants unless the language is detected
+	if language == "auto" {
+		setList("preferredVariants", o.PreferredVariants)
+	}
+	setList("enabledRules", o.EnabledRules)
+	setList("disabledRules", o.DisabledRules)
+	setList("enabledCategories", o.EnabledCategories)
+	setList("disabledCategories", o.DisabledCategories)
+	if o.Level != "" {
+		values.Set("level", o.Level)
+	}
+	return values
+}
+
+func union(a []string, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	result := append([]string{}, a...)
+	for _, v := range b {
+		found := false
+		for _, existing := range result {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, v)
+		}
+	}
+	return result
+}
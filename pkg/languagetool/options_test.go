@@ -0,0 +1,28 @@
+package languagetool
+
+import (
+	"testing"
+)
+
+func TestCheckOptionsMergeEnabledOnly(t *testing.T) {
+	enabled, disabled := true, false
+	user := CheckOptions{EnabledOnly: &enabled, EnabledRules: []string{"RULE"}}
+
+	tests := []struct {
+		other  CheckOptions
+		expect string
+	}{
+		{other: CheckOptions{}, expect: "true"},
+		{other: CheckOptions{EnabledOnly: &disabled}, expect: "false"},
+	}
+
+	for _, test := range tests {
+		merged := user.Merge(test.other)
+		if got := merged.values().Get("enabledOnly"); got != test.expect {
+			t.Fatalf("wrong enabledOnly after merging %+v want: %s, got: %s", test.other, test.expect, got)
+		}
+	}
+	if got := (CheckOptions{}).values().Get("enabledOnly"); got != "false" {
+		t.Fatalf("wrong default of enabledOnly want: false, got: %s", got)
+	}
+}
@@ -0,0 +1,118 @@
+package languagetool
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// paragraph is a part of a text with its offset in the text. LanguageTool is
+// written in Java and counts offsets in UTF-16 code units, so the offset is
+// kept in both units.
+type paragraph struct {
+	text     string
+	offset   int
+	offset16 int
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// splitParagraphs splits text after blank lines. Every paragraph keeps its
+// trailing line breaks, so joining the paragraphs results in text again.
+func splitParagraphs(text string) []paragraph {
+	paragraphs := []paragraph{}
+	current := paragraph{}
+	blank := false
+	for _, line := range splitLines(text) {
+		isBlank := strings.TrimSpace(line.text) == ""
+		// the first line with text after blank lines starts a new paragraph
+		if blank && !isBlank {
+			current.text = text[current.offset:line.offset]
+			paragraphs = append(paragraphs, current)
+			current = line
+		}
+		blank = isBlank
+	}
+	if current.offset < len(text) {
+		current.text = text[current.offset:]
+		paragraphs = append(paragraphs, current)
+	}
+	return paragraphs
+}
+
+// splitLines splits text after each line break.
+func splitLines(text string) []paragraph {
+	return splitAfter(text, func(rest string) int {
+		i := strings.Index(rest, "\n")
+		if i < 0 {
+			return -1
+		}
+		return i + 1
+	})
+}
+
+// splitSentences splits text after a sentence end followed by a space.
+func splitSentences(text string) []paragraph {
+	return splitAfter(text, func(rest string) int {
+		i := strings.IndexAny(rest, ".!?")
+		for i >= 0 {
+			end := i + 1
+			if end < len(rest) && rest[end] == ' ' {
+				return end + 1
+			}
+			next := strings.IndexAny(rest[end:], ".!?")
+			if next < 0 {
+				return -1
+			}
+			i = end + next
+		}
+		return -1
+	})
+}
+
+// splitAfter splits text at the positions returned by next, which returns
+// the length of the next part or -1 if rest is the last one.
+func splitAfter(text string, next func(rest string) int) []paragraph {
+	paragraphs := []paragraph{}
+	offset, offset16 := 0, 0
+	for offset < len(text) {
+		end := next(text[offset:])
+		if end <= 0 || offset+end > len(text) {
+			end = len(text) - offset
+		}
+		part := text[offset : offset+end]
+		paragraphs = append(paragraphs, paragraph{text: part, offset: offset, offset16: offset16})
+		offset += end
+		offset16 += utf16Len(part)
+	}
+	return paragraphs
+}
+
+// splitBytes splits text into parts of at most max bytes without breaking
+// a rune apart.
+func splitBytes(text string, max int) []paragraph {
+	return splitAfter(text, func(rest string) int {
+		if len(rest) <= max {
+			return -1
+		}
+		end := max
+		for end > 0 && !utf8.RuneStart(rest[end]) {
+			end--
+		}
+		if end == 0 {
+			_, size := utf8.DecodeRuneInString(rest)
+			return size
+		}
+		return end
+	})
+}
@@ -0,0 +1,230 @@
+package languagetool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Plan describes the limits of a LanguageTool account, zero values mean
+// unlimited. See https://languagetool.org/http-api/ for the current limits.
+type Plan struct {
+	RequestsPerMinute int
+	BytesPerMinute    int
+	// MaxRequestBytes is the maximum size of the text of a single request.
+	MaxRequestBytes int
+}
+
+var (
+	FreePlan      = Plan{RequestsPerMinute: 20, BytesPerMinute: 75_000, MaxRequestBytes: 20_000}
+	PremiumPlan   = Plan{RequestsPerMinute: 80, BytesPerMinute: 300_000, MaxRequestBytes: 60_000}
+	UnlimitedPlan = Plan{}
+)
+
+// PlanByName returns the plan for "free", "premium" or "unlimited" (e.g.
+// for a self-hosted server).
+func PlanByName(name string) (Plan, error) {
+	switch name {
+	case "free":
+		return FreePlan, nil
+	case "premium":
+		return PremiumPlan, nil
+	case "unlimited":
+		return UnlimitedPlan, nil
+	default:
+		return Plan{}, fmt.Errorf("unknown plan %q, expected free, premium or unlimited", name)
+	}
+}
+
+type usage struct {
+	at    time.Time
+	bytes int
+}
+
+type call struct {
+	done   chan struct{}
+	result CheckResult
+	err    error
+}
+
+// RateLimiter wraps a LanguagetoolApi and keeps the requests within the
+// quota of a plan. Requests over budget are queued, identical requests which
+// are queued or in flight are coalesced into one. Requests which failed
+// because of the rate limit or a server error are retried with a jittered
+// exponential backoff.
+type RateLimiter struct {
+	log     *zap.Logger
+	api     LanguagetoolApi
+	plan    Plan
+	retries int
+
+	window  time.Duration
+	backoff time.Duration
+
+	turn chan struct{}
+
+	mu      sync.Mutex
+	history []usage
+	calls   map[string]*call
+}
+
+func NewRateLimiter(log *zap.Logger, api LanguagetoolApi, plan Plan, retries int) *RateLimiter {
+	return &RateLimiter{
+		log:     log,
+		api:     api,
+		plan:    plan,
+		retries: retries,
+		window:  time.Minute,
+		backoff: time.Second,
+		turn:    make(chan struct{}, 1),
+		calls:   map[string]*call{},
+	}
+}
+
+func (r *RateLimiter) CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+	return r.CheckAnnotatedText(ctx, PlainText(text), options)
+}
+
+func (r *RateLimiter) CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error) {
+	param, value := text.param()
+	key := options.values().Encode() + "\x00" + param + "\x00" + value
+
+	r.mu.Lock()
+	if c, ok := r.calls[key]; ok {
+		r.mu.Unlock()
+		select {
+		case <-c.done:
+			// the request was cancelled by the other caller, not by us
+			if errors.Is(c.err, context.Canceled) && ctx.Err() == nil {
+				return r.CheckAnnotatedText(ctx, text, options)
+			}
+			return c.result, c.err
+		case <-ctx.Done():
+			return CheckResult{}, ctx.Err()
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	r.calls[key] = c
+	r.mu.Unlock()
+
+	c.result, c.err = r.checkWithRetries(ctx, text, len(value), options)
+
+	r.mu.Lock()
+	delete(r.calls, key)
+	r.mu.Unlock()
+	close(c.done)
+
+	return c.result, c.err
+}
+
+func (r *RateLimiter) checkWithRetries(ctx context.Context, text AnnotatedText, bytes int, options CheckOptions) (CheckResult, error) {
+	for attempt := 0; ; attempt++ {
+		if err := r.wait(ctx, bytes); err != nil {
+			return CheckResult{}, err
+		}
+
+		result, err := r.api.CheckAnnotatedText(ctx, text, options)
+		if err == nil || attempt >= r.retries || !retryable(err) {
+			return result, err
+		}
+
+		delay, ok := RetryAfter(err)
+		if !ok {
+			delay = r.backoffDelay(attempt)
+		}
+		r.log.Debug(fmt.Sprintf("retrying check in %s: %s", delay, err))
+
+		if err := sleep(ctx, delay); err != nil {
+			return CheckResult{}, err
+		}
+	}
+}
+
+// wait blocks until a request of size bytes fits into the plan. Waiting
+// requests are served in order.
+func (r *RateLimiter) wait(ctx context.Context, bytes int) error {
+	select {
+	case r.turn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-r.turn }()
+
+	for {
+		r.mu.Lock()
+		delay := r.delay(time.Now(), bytes)
+		if delay <= 0 {
+			r.history = append(r.history, usage{at: time.Now(), bytes: bytes})
+			r.mu.Unlock()
+			return nil
+		}
+		r.mu.Unlock()
+
+		r.log.Debug(fmt.Sprintf("rate limit reached, waiting %s", delay))
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// delay returns how long to wait until a request of size bytes fits into the
+// window. A request larger than the whole budget is let through once the
+// window is empty.
+func (r *RateLimiter) delay(now time.Time, bytes int) time.Duration {
+	for len(r.history) > 0 && now.Sub(r.history[0].at) >= r.window {
+		r.history = r.history[1:]
+	}
+	if len(r.history) == 0 {
+		return 0
+	}
+
+	if r.plan.RequestsPerMinute > 0 && len(r.history) >= r.plan.RequestsPerMinute {
+		return r.history[len(r.history)-r.plan.RequestsPerMinute].at.Add(r.window).Sub(now)
+	}
+
+	if r.plan.BytesPerMinute > 0 {
+		used := 0
+		for _, u := range r.history {
+			used += u.bytes
+		}
+		// free the oldest entries until the request fits
+		for _, u := range r.history {
+			if used+bytes <= r.plan.BytesPerMinute {
+				break
+			}
+			used -= u.bytes
+			if used+bytes <= r.plan.BytesPerMinute || used == 0 {
+				return u.at.Add(r.window).Sub(now)
+			}
+		}
+	}
+	return 0
+}
+
+func (r *RateLimiter) backoffDelay(attempt int) time.Duration {
+	max := r.backoff << attempt
+	if max > r.window || max <= 0 {
+		max = r.window
+	}
+	return max/2 + time.Duration(rand.Int63n(int64(max/2)+1))
+}
+
+func retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,146 @@
+package languagetool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type apiFunc func(ctx context.Context, text string, options CheckOptions) (CheckResult, error)
+
+func (f apiFunc) CheckText(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+	return f(ctx, text, options)
+}
+
+func (f apiFunc) CheckAnnotatedText(ctx context.Context, text AnnotatedText, options CheckOptions) (CheckResult, error) {
+	return f(ctx, text.String(), options)
+}
+
+func newTestRateLimiter(api LanguagetoolApi, plan Plan) *RateLimiter {
+	limiter := NewRateLimiter(zap.NewNop(), api, plan, 3)
+	limiter.window = 100 * time.Millisecond
+	limiter.backoff = time.Millisecond
+	return limiter
+}
+
+func TestRateLimiterQueuesRequests(t *testing.T) {
+	tests := []struct {
+		plan  Plan
+		texts []string
+	}{
+		{plan: Plan{RequestsPerMinute: 2}, texts: []string{"a", "b", "c"}},
+		{plan: Plan{BytesPerMinute: 10}, texts: []string{"aaaaaa", "bbbbbb"}},
+	}
+
+	for _, test := range tests {
+		var calls []time.Time
+		limiter := newTestRateLimiter(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+			calls = append(calls, time.Now())
+			return CheckResult{}, nil
+		}), test.plan)
+
+		for _, text := range test.texts {
+			if _, err := limiter.CheckText(context.Background(), text, CheckOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if waited := calls[len(calls)-1].Sub(calls[0]); waited < 90*time.Millisecond {
+			t.Fatalf("last request of %+v was not delayed, waited: %s", test.plan, waited)
+		}
+	}
+}
+
+func TestRateLimiterRetries(t *testing.T) {
+	calls := 0
+	limiter := newTestRateLimiter(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		calls++
+		switch calls {
+		case 1:
+			return CheckResult{}, &APIError{StatusCode: 429, RetryAfter: 5 * time.Millisecond, err: ErrRateLimited}
+		case 2:
+			return CheckResult{}, &APIError{StatusCode: 502, err: ErrServer}
+		default:
+			return CheckResult{Matches: []Match{{Message: "ok"}}}, nil
+		}
+	}), UnlimitedPlan)
+
+	result, err := limiter.CheckText(context.Background(), "text", CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(result.Matches) != 1 {
+		t.Fatalf("wrong number of calls want: 3, got: %d", calls)
+	}
+}
+
+func TestRateLimiterDoesNotRetryUnauthorized(t *testing.T) {
+	calls := 0
+	limiter := newTestRateLimiter(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		calls++
+		return CheckResult{}, &APIError{StatusCode: 401, err: ErrUnauthorized}
+	}), UnlimitedPlan)
+
+	if _, err := limiter.CheckText(context.Background(), "text", CheckOptions{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Fatalf("wrong number of calls want: 1, got: %d", calls)
+	}
+}
+
+// waitingContext reports when a request waits for it to be done.
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan<- struct{}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.once.Do(func() { c.waiting <- struct{}{} })
+	return c.Context.Done()
+}
+
+func TestRateLimiterCoalescesRequests(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	limiter := newTestRateLimiter(apiFunc(func(ctx context.Context, text string, options CheckOptions) (CheckResult, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return CheckResult{}, nil
+	}), UnlimitedPlan)
+
+	wg := sync.WaitGroup{}
+	check := func(ctx context.Context) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.CheckText(ctx, "same text", CheckOptions{})
+		}()
+	}
+
+	check(context.Background())
+	<-started
+	// the first request is in flight until it is released, so the others
+	// wait for it
+	waiting := make(chan struct{}, 4)
+	for i := 0; i < 4; i++ {
+		check(&waitingContext{Context: context.Background(), waiting: waiting})
+	}
+	for i := 0; i < 4; i++ {
+		<-waiting
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("wrong number of calls want: 1, got: %d", calls.Load())
+	}
+}
@@ -0,0 +1,69 @@
+package languagetool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WordsApi manages the personal dictionary of a LanguageTool account. Words
+// in it are no longer reported as misspellings by the hosted API.
+type WordsApi interface {
+	AddWord(ctx context.Context, word string) error
+}
+
+// AddWord adds a word to the dictionary of the account, it requires
+// credentials of a premium account.
+func (c Client) AddWord(ctx context.Context, word string) error {
+	if c.credentials.Username == "" {
+		return fmt.Errorf("languagetool: %w: adding words requires credentials", ErrUnauthorized)
+	}
+
+	formData := url.Values{}
+	formData.Set("word", word)
+	formData.Set("username", c.credentials.Username)
+	formData.Set("apiKey", c.credentials.ApiToken)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"words/add", strings.NewReader(formData.Encode()))
+	if err != nil {
+		c.log.Error(err.Error())
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.log.Error(err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Error(err.Error())
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError(resp, body)
+		c.log.Error(apiErr.Error())
+		return apiErr
+	}
+
+	result := struct {
+		Added bool `json:"added"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		c.log.Error(err.Error())
+		return fmt.Errorf("languagetool: %w: %w", ErrDecode, err)
+	}
+	if !result.Added {
+		return fmt.Errorf("languagetool: word %q was not added", word)
+	}
+	return nil
+}